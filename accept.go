@@ -0,0 +1,122 @@
+package celerity
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// AcceptEntry is a single value of an Accept, Accept-Encoding or similar
+// header with its quality.
+type AcceptEntry struct {
+	Value string
+	Q     float64
+}
+
+// AcceptHeader is a parsed Accept-* header. Entries keep the order they
+// appear in the header, including entries with a quality of zero, which
+// exclude a value.
+type AcceptHeader []AcceptEntry
+
+// ParseAccept parses an Accept-* header such as
+// "text/html, application/json;q=0.9, */*;q=0" or "gzip, br;q=0.5, *;q=0".
+// Values are lower cased and parameters other than q are ignored.
+func ParseAccept(header string) AcceptHeader {
+	entries := AcceptHeader{}
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		value := strings.ToLower(strings.TrimSpace(params[0]))
+		if value == "" {
+			continue
+		}
+		entry := AcceptEntry{Value: value, Q: 1}
+		for _, p := range params[1:] {
+			kv := strings.SplitN(strings.TrimSpace(p), "=", 2)
+			if len(kv) == 2 && strings.ToLower(strings.TrimSpace(kv[0])) == "q" {
+				if q, err := strconv.ParseFloat(strings.TrimSpace(kv[1]), 64); err == nil {
+					entry.Q = q
+				}
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// Match returns the most specific entry that matches a value along with its
+// position in the header. An exact match takes precedence over a "type/*"
+// range, which takes precedence over "*" or "*/*", regardless of their
+// qualities. The position is -1 if no entry matches.
+func (h AcceptHeader) Match(value string) (AcceptEntry, int) {
+	value = strings.ToLower(value)
+	best, bestIdx, bestRank := AcceptEntry{}, -1, 0
+	for i, entry := range h {
+		rank := acceptRank(entry.Value, value)
+		if rank > bestRank {
+			best, bestIdx, bestRank = entry, i, rank
+		}
+	}
+	return best, bestIdx
+}
+
+// Quality returns the quality of a value. Values that are not matched by any
+// entry have a quality of zero.
+func (h AcceptHeader) Quality(value string) float64 {
+	entry, idx := h.Match(value)
+	if idx < 0 {
+		return 0
+	}
+	return entry.Q
+}
+
+// Accepts checks if a value is acceptable, meaning its most specific entry
+// has a quality above zero.
+func (h AcceptHeader) Accepts(value string) bool {
+	return h.Quality(value) > 0
+}
+
+// Preferred orders the acceptable values by quality and then by the position
+// of the entry that matched them. Values with equal preference keep their
+// order. Values that are not acceptable are left out.
+func (h AcceptHeader) Preferred(values []string) []string {
+	type candidate struct {
+		value string
+		q     float64
+		idx   int
+	}
+	candidates := []candidate{}
+	for _, v := range values {
+		entry, idx := h.Match(v)
+		if idx < 0 || entry.Q <= 0 {
+			continue
+		}
+		candidates = append(candidates, candidate{v, entry.Q, idx})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].q != candidates[j].q {
+			return candidates[i].q > candidates[j].q
+		}
+		return candidates[i].idx < candidates[j].idx
+	})
+	preferred := make([]string, len(candidates))
+	for i, c := range candidates {
+		preferred[i] = c.value
+	}
+	return preferred
+}
+
+// acceptRank scores how specifically a header entry matches a value. Zero
+// means the entry does not match.
+func acceptRank(entry, value string) int {
+	switch {
+	case entry == value:
+		return 3
+	case entry == "*" || entry == "*/*":
+		return 1
+	case strings.HasSuffix(entry, "/*"):
+		if strings.HasPrefix(value, strings.TrimSuffix(entry, "*")) {
+			return 2
+		}
+	}
+	return 0
+}
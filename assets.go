@@ -0,0 +1,181 @@
+package celerity
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/afero"
+)
+
+// immutableCacheControl is sent with fingerprinted assets. Their URLs change
+// whenever their content does so they can be cached forever.
+const immutableCacheControl = "public, max-age=31536000, immutable"
+
+// AssetRoute serves fingerprinted assets. Each file under the local path is
+// available at a URL containing a hash of its contents, such as
+// /assets/app.3f2a9c81d04e.js for app.js. Fingerprinted files are served with
+// immutable cache headers and gzipped siblings are served to clients that
+// accept them.
+type AssetRoute struct {
+	Path      RoutePath
+	LocalPath string
+	// Manifest maps logical file names to their fingerprinted names.
+	Manifest map[string]string
+	files    map[string]string
+}
+
+// NewAssetRoute fingerprints the files under a local path. The manifest is
+// kept in memory and nothing is written to the local path. Use WriteManifest
+// to save it for build tools or other servers.
+func NewAssetRoute(path, localpath string) (*AssetRoute, error) {
+	r := &AssetRoute{
+		Path:      RoutePath(path),
+		LocalPath: localpath,
+		Manifest:  map[string]string{},
+		files:     map[string]string{},
+	}
+	fs := FSAdapter.RootPath(localpath)
+	err := afero.Walk(fs, "/", func(fpath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		name := strings.TrimPrefix(filepath.ToSlash(fpath), "/")
+		if info.IsDir() || strings.HasSuffix(name, ".gz") {
+			return nil
+		}
+		hash, err := hashFile(fs, fpath)
+		if err != nil {
+			return err
+		}
+		r.Manifest[name] = fingerprint(name, hash)
+		r.files[r.Manifest[name]] = name
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Match checks if the path is a fingerprinted or logical asset name.
+func (r *AssetRoute) Match(method string, path string) (bool, string) {
+	if method != GET && method != HEAD {
+		return false, path
+	}
+	if len(path) < len(r.Path) {
+		return false, path
+	}
+	if ok, _ := r.Path.Match(path); !ok {
+		return false, path
+	}
+	name := strings.TrimPrefix(path[len(r.Path):], "/")
+	if _, ok := r.files[name]; ok {
+		return true, ""
+	}
+	if _, ok := r.Manifest[name]; ok {
+		return true, ""
+	}
+	return false, path
+}
+
+// Handle serves the asset. Fingerprinted names are served with immutable
+// cache headers.
+func (r *AssetRoute) Handle(c Context) Response {
+	name := strings.TrimPrefix(c.ScopedPath[len(r.Path):], "/")
+	config := StaticConfig{Precompressed: true}
+	if logical, ok := r.files[name]; ok {
+		name = logical
+		config.CacheControl = immutableCacheControl
+	}
+	serveFile(c, r.LocalPath, "/"+name, config)
+	return Response{Handled: true}
+}
+
+// RoutePath returns the route path for the route.
+func (r *AssetRoute) RoutePath() RoutePath {
+	return r.Path
+}
+
+// URL returns the fingerprinted URL for a logical asset name. It returns false
+// if the asset is not in the manifest.
+func (r *AssetRoute) URL(name string) (string, bool) {
+	fp, ok := r.Manifest[strings.TrimPrefix(name, "/")]
+	if !ok {
+		return "", false
+	}
+	return path.Join("/", string(r.Path), fp), true
+}
+
+// WriteManifest writes the manifest as a JSON object mapping logical file names
+// to their fingerprinted names.
+//
+//	f, err := os.Create("public/manifest.json")
+//	...
+//	err = route.WriteManifest(f)
+func (r *AssetRoute) WriteManifest(w io.Writer) error {
+	buf, err := json.MarshalIndent(r.Manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(buf, '\n'))
+	return err
+}
+
+// ServeAssets fingerprints the files under a local path and serves them at the
+// given path. Use AssetURL to build links to the assets. The returned route
+// can write the manifest with WriteManifest.
+func (s *Server) ServeAssets(path, rootpath string) (*AssetRoute, error) {
+	r, err := NewAssetRoute(path, rootpath)
+	if err != nil {
+		return nil, err
+	}
+	s.assets = append(s.assets, r)
+	s.Router.Root.Routes = append(s.Router.Root.Routes, r)
+	return r, nil
+}
+
+// AssetURL returns the fingerprinted URL for a logical asset name, such as
+// "app.js" or "css/site.css". If the asset is not known the name is returned
+// unchanged.
+func (s *Server) AssetURL(name string) string {
+	for _, r := range s.assets {
+		if u, ok := r.URL(name); ok {
+			return u
+		}
+	}
+	return name
+}
+
+// AssetURL returns the fingerprinted URL for a logical asset name.
+func (c *Context) AssetURL(name string) string {
+	if c.Server == nil {
+		return name
+	}
+	return c.Server.AssetURL(name)
+}
+
+// hashFile returns the first twelve hex characters of the SHA-256 of a file.
+func hashFile(fs afero.Fs, fpath string) (string, error) {
+	f, err := fs.Open(fpath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil))[:12], nil
+}
+
+// fingerprint inserts a hash before the extension of a file name.
+func fingerprint(name, hash string) string {
+	ext := path.Ext(name)
+	return strings.TrimSuffix(name, ext) + "." + hash + ext
+}
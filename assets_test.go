@@ -0,0 +1,156 @@
+package celerity
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+func TestServeAssets(t *testing.T) {
+	adapter := NewMEMAdapter()
+	FSAdapter = adapter
+	afero.WriteFile(adapter.MEMFS, "/static/app.js", []byte("render()"), 0755)
+	afero.WriteFile(adapter.MEMFS, "/static/app.js.gz", gzipped(t, "render()"), 0755)
+	afero.WriteFile(adapter.MEMFS, "/static/css/site.css", []byte("body {}"), 0755)
+	afero.WriteFile(adapter.MEMFS, "/views/layouts/main.html", []byte(`{{ template "content" . }}`), 0755)
+	afero.WriteFile(adapter.MEMFS, "/views/index.html", []byte(`<script src="{{ asset "app.js" }}"></script>`), 0755)
+
+	server := New()
+	route, err := server.ServeAssets("/assets", "/static")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	server.SetViews(NewViewEngine("/views"))
+	server.GET("/page", func(c Context) Response {
+		return c.Render("index", nil)
+	})
+	server.GET("/url", func(c Context) Response {
+		return c.Raw([]byte(c.AssetURL("css/site.css")))
+	})
+
+	jsURL := server.AssetURL("app.js")
+	if !strings.HasPrefix(jsURL, "/assets/app.") || !strings.HasSuffix(jsURL, ".js") ||
+		len(jsURL) != len("/assets/app.000000000000.js") {
+		t.Fatalf("asset url was %s", jsURL)
+	}
+	if v := server.AssetURL("missing.js"); v != "missing.js" {
+		t.Errorf("unknown asset url was %s", v)
+	}
+
+	t.Run("manifest", func(t *testing.T) {
+		if ok, _ := afero.Exists(adapter.MEMFS, "/static/manifest.json"); ok {
+			t.Error("manifest was written to the asset directory")
+		}
+		manifest := server.assets[0].Manifest
+		if "/assets/"+manifest["app.js"] != jsURL {
+			t.Errorf("manifest was %v", manifest)
+		}
+		if _, ok := manifest["app.js.gz"]; ok {
+			t.Errorf("manifest included compressed file: %v", manifest)
+		}
+		if !strings.HasPrefix(manifest["css/site.css"], "css/site.") {
+			t.Errorf("manifest was %v", manifest)
+		}
+
+		var buf bytes.Buffer
+		if err := route.WriteManifest(&buf); err != nil {
+			t.Fatal(err.Error())
+		}
+		written := map[string]string{}
+		if err := json.Unmarshal(buf.Bytes(), &written); err != nil {
+			t.Fatalf("manifest was not JSON: %s", buf.String())
+		}
+		if !reflect.DeepEqual(written, manifest) {
+			t.Errorf("written manifest was %s", buf.String())
+		}
+	})
+
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	get := func(path, encoding string) (*http.Response, string) {
+		req, _ := http.NewRequest(GET, ts.URL+path, nil)
+		req.Header.Set("Accept-Encoding", encoding)
+		res, err := http.DefaultTransport.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("Error requesting url: %s", err.Error())
+		}
+		defer res.Body.Close()
+		bbody, _ := ioutil.ReadAll(res.Body)
+		return res, string(bbody)
+	}
+
+	t.Run("fingerprinted", func(t *testing.T) {
+		res, body := get(jsURL, "identity")
+		if body != "render()" {
+			t.Errorf("body was: %s", body)
+		}
+		if v := res.Header.Get("Cache-Control"); v != "public, max-age=31536000, immutable" {
+			t.Errorf("cache control was %s", v)
+		}
+	})
+
+	t.Run("precompressed", func(t *testing.T) {
+		res, body := get(jsURL, "gzip")
+		if v := res.Header.Get("Content-Encoding"); v != "gzip" {
+			t.Fatalf("content encoding was %s", v)
+		}
+		r, err := gzip.NewReader(strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		plain, _ := ioutil.ReadAll(r)
+		if string(plain) != "render()" {
+			t.Errorf("body was: %s", string(plain))
+		}
+	})
+
+	t.Run("gzip excluded", func(t *testing.T) {
+		res, body := get(jsURL, "gzip;q=0, *")
+		if v := res.Header.Get("Content-Encoding"); v != "" {
+			t.Errorf("content encoding was %s", v)
+		}
+		if body != "render()" {
+			t.Errorf("body was: %s", body)
+		}
+	})
+
+	t.Run("logical name", func(t *testing.T) {
+		res, body := get("/assets/css/site.css", "")
+		if body != "body {}" {
+			t.Errorf("body was: %s", body)
+		}
+		if v := res.Header.Get("Cache-Control"); v != "" {
+			t.Errorf("cache control was %s", v)
+		}
+	})
+
+	t.Run("helpers", func(t *testing.T) {
+		_, body := get("/page", "")
+		if body != `<script src="`+jsURL+`"></script>` {
+			t.Errorf("body was: %s", body)
+		}
+		_, body = get("/url", "")
+		if body != server.AssetURL("css/site.css") || body == "css/site.css" {
+			t.Errorf("body was: %s", body)
+		}
+	})
+}
+
+func gzipped(t *testing.T, s string) []byte {
+	var buf strings.Builder
+	w := gzip.NewWriter(&buf)
+	w.Write([]byte(s))
+	if err := w.Close(); err != nil {
+		t.Fatal(err.Error())
+	}
+	return []byte(buf.String())
+}
@@ -1,6 +1,7 @@
 package celerity
 
 import (
+	"github.com/5Sigma/vox"
 	"github.com/spf13/afero"
 	"github.com/spf13/viper"
 )
@@ -18,6 +19,8 @@ var (
 	DELETE = "POST"
 	// OPTIONS verb for HTTP request
 	OPTIONS = "OPTIONS"
+	// HEAD verb for HTTP request
+	HEAD = "HEAD"
 	// ANY can be used to match any method
 	ANY = "*"
 	//DEV is the development value for the environment flag
@@ -34,7 +37,13 @@ func New() *Server {
 	return s
 }
 
-// SetEnvironment sets the currently operating environment.
+// SetEnvironment sets the currently operating environment. If the config file
+// has been reloaded, the configuration is reloaded for the new environment.
 func SetEnvironment(env string) {
 	viper.Set("env", env)
+	if loadedConfig() != nil {
+		if err := reloadConfig(); err != nil {
+			vox.Error(err)
+		}
+	}
 }
@@ -0,0 +1,97 @@
+package celerity
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// Command is an application command added to the CLI built by HandleCLI.
+//
+//	celerity.AddCommand(celerity.Command{
+//		Use:   "migrate",
+//		Short: "Run database migrations",
+//		Flags: func(flags *pflag.FlagSet) {
+//			flags.Bool("dry-run", false, "Print migrations without running them")
+//		},
+//		Run: func(server *celerity.Server, config *viper.Viper, args []string) error {
+//			return migrate(config.GetString("database"), config.GetBool("dry-run"))
+//		},
+//	})
+type Command struct {
+	// Use is the one line usage message. The first word is the command name.
+	Use string
+	// Short is the description shown in the help output.
+	Short string
+	// Long is the description shown in the command's help.
+	Long string
+	// Flags defines flags for the command. They are bound to viper using
+	// their names when the command runs.
+	Flags func(*pflag.FlagSet)
+	// Run executes the command with the server returned by the HandleCLI
+	// callback, the loaded configuration and the remaining arguments.
+	Run func(server *Server, config *viper.Viper, args []string) error
+}
+
+var (
+	commands = []Command{}
+	cliFlags = pflag.NewFlagSet("celerity", pflag.ContinueOnError)
+)
+
+// AddCommand adds an application command to the CLI. Commands must be added
+// before HandleCLI is called.
+func AddCommand(cmd Command) {
+	commands = append(commands, cmd)
+}
+
+// CLIFlags returns the persistent flags available to every command. Flags
+// defined on it are bound to viper using their names and must be defined
+// before HandleCLI is called. A flag with the same name as one of a command's
+// own flags, such as the run command's port, is shadowed by it for that
+// command.
+//
+//	celerity.CLIFlags().String("database", "postgres://localhost/app", "Database URL")
+func CLIFlags() *pflag.FlagSet {
+	return cliFlags
+}
+
+// addCommands adds the application commands and persistent flags to the root
+// command. Flags are bound to viper when a command runs, so only the flags of
+// that command are bound and flags of other commands cannot replace them.
+func addCommands(rootCmd *cobra.Command, onRun func() *Server) {
+	rootCmd.PersistentFlags().AddFlagSet(cliFlags)
+	for _, c := range commands {
+		run := c.Run
+		cmd := &cobra.Command{
+			Use:   c.Use,
+			Short: c.Short,
+			Long:  c.Long,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return run(onRun(), viper.GetViper(), args)
+			},
+		}
+		if c.Flags != nil {
+			c.Flags(cmd.Flags())
+		}
+		rootCmd.AddCommand(cmd)
+	}
+	for _, cmd := range rootCmd.Commands() {
+		if cmd.PreRunE == nil && cmd.PreRun == nil {
+			cmd.PreRunE = bindFlags
+		}
+	}
+}
+
+// configFlags are the flags bound by bindFlags. They keep precedence over the
+// config files when the configuration is reloaded.
+var configFlags *pflag.FlagSet
+
+// bindFlags binds the flags of the running command, including the persistent
+// flags it inherits, to the viper keys of the same name.
+func bindFlags(cmd *cobra.Command, args []string) error {
+	configFlags = cmd.Flags()
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		viper.BindPFlag(f.Name, f)
+	})
+	return nil
+}
@@ -0,0 +1,156 @@
+package celerity
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ETag sets the entity tag for the response. The tag is quoted if needed.
+// Tagged GET responses are answered with 304 Not Modified when the client
+// already has the representation.
+func (r Response) ETag(tag string) Response {
+	if r.Header == nil {
+		r.Header = http.Header{}
+	}
+	if !strings.HasSuffix(tag, `"`) {
+		tag = `"` + tag + `"`
+	}
+	r.Header.Set("ETag", tag)
+	return r
+}
+
+// LastModified sets the modification time of the response. GET responses are
+// answered with 304 Not Modified when the resource has not changed since the
+// time sent by the client in If-Modified-Since.
+func (r Response) LastModified(t time.Time) Response {
+	if r.Header == nil {
+		r.Header = http.Header{}
+	}
+	r.Header.Set("Last-Modified", t.UTC().Format(http.TimeFormat))
+	return r
+}
+
+// CheckPreconditions evaluates the If-Match and If-Unmodified-Since headers of
+// the request against the current entity tag and modification time of a
+// resource. It returns false if the request should not be processed. Either
+// value can be left empty to skip its check. Handlers of unsafe methods call it
+// with the current validators of the resource they change:
+//
+//	func updateDoc(c celerity.Context) celerity.Response {
+//		doc := loadDoc(c.URLParams.String("id"))
+//		if !c.CheckPreconditions(doc.Version, doc.UpdatedAt) {
+//			return c.PreconditionFailed()
+//		}
+//		...
+//	}
+func (c *Context) CheckPreconditions(etag string, modified time.Time) bool {
+	if im := c.Header("If-Match"); im != "" {
+		if etag == "" {
+			return false
+		}
+		if !strings.HasSuffix(etag, `"`) {
+			etag = `"` + etag + `"`
+		}
+		return matchETag(im, etag, false)
+	}
+	if ius := c.Header("If-Unmodified-Since"); ius != "" && !modified.IsZero() {
+		t, err := http.ParseTime(ius)
+		if err != nil {
+			return true
+		}
+		return !modified.Truncate(time.Second).After(t)
+	}
+	return true
+}
+
+// PreconditionFailed returns a 412 Precondition Failed error response.
+func (c *Context) PreconditionFailed() Response {
+	return c.Error(http.StatusPreconditionFailed, errors.New("precondition failed"))
+}
+
+// notModified sets the ETag for a successful GET or HEAD response and checks
+// the conditional headers of the request. If the client's copy is current a
+// 304 Not Modified is written and true is returned.
+//
+// When the server has ETags enabled and the handler did not supply a tag, one
+// is generated from the encoded body.
+func (s *Server) notModified(c Context, resp Response, body []byte) bool {
+	if c.Request.Method != GET && c.Request.Method != HEAD {
+		return false
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false
+	}
+	h := c.Writer.Header()
+	etag := h.Get("ETag")
+	if etag == "" && s.ETags {
+		etag = generateETag(body, c.RequestID)
+		h.Set("ETag", etag)
+	}
+
+	if inm := c.Header("If-None-Match"); inm != "" {
+		if etag == "" || !matchETag(inm, etag, true) {
+			return false
+		}
+	} else if ims := c.Header("If-Modified-Since"); ims != "" {
+		lm, err := http.ParseTime(h.Get("Last-Modified"))
+		if err != nil {
+			return false
+		}
+		t, err := http.ParseTime(ims)
+		if err != nil || lm.After(t) {
+			return false
+		}
+	} else {
+		return false
+	}
+
+	h.Del("Content-Type")
+	h.Del("Content-Length")
+	c.Writer.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// generateETag hashes the body written by the response adapter. The request
+// ID is removed from the body first, so requests for an unchanged resource get
+// the same tag. Such a tag is weak because the bodies are equivalent rather
+// than identical. Bodies without the request ID, such as raw responses, get a
+// strong tag.
+func generateETag(body []byte, requestID string) string {
+	if requestID != "" && bytes.Contains(body, []byte(requestID)) {
+		body = bytes.Replace(body, []byte(requestID), nil, -1)
+		return fmt.Sprintf(`W/"%x"`, sha1.Sum(body))
+	}
+	return fmt.Sprintf(`"%x"`, sha1.Sum(body))
+}
+
+// matchETag checks an etag against a list of tags from an If-Match or
+// If-None-Match header. Weak comparison ignores the weak indicator, strong
+// comparison never matches weak tags.
+func matchETag(header, etag string, weak bool) bool {
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	if !weak && strings.HasPrefix(etag, "W/") {
+		return false
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, t := range strings.Split(header, ",") {
+		t = strings.TrimSpace(t)
+		if strings.HasPrefix(t, "W/") {
+			if !weak {
+				continue
+			}
+			t = t[2:]
+		}
+		if t == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,201 @@
+package celerity
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMatchETag(t *testing.T) {
+	tests := []struct {
+		Header string
+		ETag   string
+		Weak   bool
+		Match  bool
+	}{
+		{`"abc"`, `"abc"`, false, true},
+		{`"xyz", "abc"`, `"abc"`, false, true},
+		{`W/"abc"`, `"abc"`, true, true},
+		{`W/"abc"`, `"abc"`, false, false},
+		{`"abc"`, `W/"abc"`, false, false},
+		{`*`, `"abc"`, false, true},
+		{`"xyz"`, `"abc"`, true, false},
+	}
+	for _, test := range tests {
+		if v := matchETag(test.Header, test.ETag, test.Weak); v != test.Match {
+			t.Errorf("match for %s against %s was %v", test.ETag, test.Header, v)
+		}
+	}
+}
+
+func TestConditionalGet(t *testing.T) {
+	modified := time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+	server := New()
+	server.ETags = true
+	server.GET("/generated", func(c Context) Response {
+		return c.R("test")
+	})
+	server.GET("/tagged", func(c Context) Response {
+		return c.R("test").ETag("v1").LastModified(modified)
+	})
+
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	get := func(path string, header http.Header) *http.Response {
+		req, _ := http.NewRequest(GET, ts.URL+path, nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Error requesting url: %s", err.Error())
+		}
+		res.Body.Close()
+		return res
+	}
+
+	t.Run("generated", func(t *testing.T) {
+		res := get("/generated", nil)
+		etag := res.Header.Get("ETag")
+		if etag == "" {
+			t.Fatal("etag not generated")
+		}
+		res = get("/generated", http.Header{"If-None-Match": {etag}})
+		if res.StatusCode != http.StatusNotModified {
+			t.Errorf("status code was %d", res.StatusCode)
+		}
+	})
+
+	t.Run("supplied", func(t *testing.T) {
+		res := get("/tagged", nil)
+		if v := res.Header.Get("ETag"); v != `"v1"` {
+			t.Errorf("etag was %s", v)
+		}
+		res = get("/tagged", http.Header{"If-None-Match": {`"v2"`}})
+		if res.StatusCode != 200 {
+			t.Errorf("status code was %d", res.StatusCode)
+		}
+		res = get("/tagged", http.Header{"If-None-Match": {`"v1"`}})
+		if res.StatusCode != http.StatusNotModified {
+			t.Errorf("status code was %d", res.StatusCode)
+		}
+	})
+
+	t.Run("modified since", func(t *testing.T) {
+		res := get("/tagged", http.Header{"If-Modified-Since": {modified.Format(http.TimeFormat)}})
+		if res.StatusCode != http.StatusNotModified {
+			t.Errorf("status code was %d", res.StatusCode)
+		}
+		earlier := modified.Add(-time.Hour).Format(http.TimeFormat)
+		res = get("/tagged", http.Header{"If-Modified-Since": {earlier}})
+		if res.StatusCode != 200 {
+			t.Errorf("status code was %d", res.StatusCode)
+		}
+	})
+}
+
+func TestConditionalUpdate(t *testing.T) {
+	modified := time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+	version, gets, updates := 1, 0, 0
+	server := New()
+	server.ETags = true
+	server.GET("/doc", func(c Context) Response {
+		gets++
+		return c.R("doc").ETag(fmt.Sprintf("v%d", version)).LastModified(modified)
+	})
+	server.PUT("/doc", func(c Context) Response {
+		if !c.CheckPreconditions(fmt.Sprintf("v%d", version), modified) {
+			return c.PreconditionFailed()
+		}
+		version++
+		updates++
+		return c.R("doc")
+	})
+
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	do := func(method string, header http.Header) *http.Response {
+		req, _ := http.NewRequest(method, ts.URL+"/doc", nil)
+		req.Header = header
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Error requesting url: %s", err.Error())
+		}
+		res.Body.Close()
+		return res
+	}
+
+	etag := do(GET, http.Header{}).Header.Get("ETag")
+	tests := []struct {
+		Header  http.Header
+		Status  int
+		Updates int
+	}{
+		{http.Header{"If-Match": {etag}}, 200, 1},
+		{http.Header{"If-Match": {etag}}, 412, 1},
+		{http.Header{"If-Match": {`"v2"`}}, 200, 2},
+		{http.Header{"If-Unmodified-Since": {modified.Add(-time.Hour).Format(http.TimeFormat)}}, 412, 2},
+		{http.Header{"If-Unmodified-Since": {modified.Format(http.TimeFormat)}}, 200, 3},
+	}
+	for _, test := range tests {
+		if res := do(PUT, test.Header); res.StatusCode != test.Status {
+			t.Errorf("%v: status code was %d", test.Header, res.StatusCode)
+		}
+		if updates != test.Updates {
+			t.Errorf("%v: handler ran %d times", test.Header, updates)
+		}
+	}
+	if gets != 1 {
+		t.Errorf("GET handler ran %d times", gets)
+	}
+}
+
+func TestGenerateETag(t *testing.T) {
+	a := generateETag([]byte(`{"requestId":"abc","data":"test"}`), "abc")
+	b := generateETag([]byte(`{"requestId":"def","data":"test"}`), "def")
+	if a != b {
+		t.Errorf("tags differ between requests: %s %s", a, b)
+	}
+	if !strings.HasPrefix(a, `W/"`) {
+		t.Errorf("tag with the request ID removed was %s", a)
+	}
+	if a == generateETag([]byte(`{"requestId":"abc","data":"other"}`), "abc") {
+		t.Error("tags match for different data")
+	}
+	if tag := generateETag([]byte("test"), "abc"); !strings.HasPrefix(tag, `"`) {
+		t.Errorf("raw tag was %s", tag)
+	}
+}
+
+func TestCheckPreconditions(t *testing.T) {
+	modified := time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		Header http.Header
+		Pass   bool
+	}{
+		{http.Header{}, true},
+		{http.Header{"If-Match": {`"v1"`}}, true},
+		{http.Header{"If-Match": {`"v0"`}}, false},
+		{http.Header{"If-Match": {`*`}}, true},
+		{http.Header{"If-Unmodified-Since": {modified.Format(http.TimeFormat)}}, true},
+		{http.Header{"If-Unmodified-Since": {modified.Add(-time.Hour).Format(http.TimeFormat)}}, false},
+	}
+	for _, test := range tests {
+		req, _ := http.NewRequest(PUT, "/doc", nil)
+		req.Header = test.Header
+		c := RequestContext(req)
+		if v := c.CheckPreconditions("v1", modified); v != test.Pass {
+			t.Errorf("preconditions for %v returned %v", test.Header, v)
+		}
+	}
+
+	c := NewContext()
+	if r := c.PreconditionFailed(); r.StatusCode != 412 {
+		t.Errorf("status code was %d", r.StatusCode)
+	}
+}
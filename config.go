@@ -0,0 +1,195 @@
+package celerity
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/5Sigma/vox"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	validator "gopkg.in/go-playground/validator.v9"
+)
+
+// SecretConfigKeys are fragments of configuration keys whose values are
+// redacted by the config command. Applications can append their own.
+var SecretConfigKeys = []string{
+	"password", "passwd", "secret", "token", "apikey", "api-key", "api_key",
+	"credentials", "private-key", "private_key",
+}
+
+const redacted = "[REDACTED]"
+
+// ConfigValue is a configuration setting and where its value came from. The
+// source is one of "flag", "env", "file" or "default".
+type ConfigValue struct {
+	Key    string      `json:"key"`
+	Value  interface{} `json:"value"`
+	Source string      `json:"source"`
+}
+
+// ConfigError is returned by LoadConfig when the configuration does not pass
+// validation. It lists every invalid setting.
+type ConfigError struct {
+	Problems []string
+}
+
+func (e *ConfigError) Error() string {
+	return "invalid configuration: " + strings.Join(e.Problems, "; ")
+}
+
+// LoadConfig unmarshals the configuration into a struct and validates it using
+// `validate` tags. Fields are matched to configuration keys by name or by
+// their `mapstructure` tag.
+//
+//	type AppConfig struct {
+//		Database string `mapstructure:"database" validate:"required,url"`
+//		Workers  int    `mapstructure:"workers" validate:"min=1"`
+//	}
+//
+//	cfg := AppConfig{}
+//	if err := celerity.LoadConfig(&cfg); err != nil {
+//		log.Fatal(err)
+//	}
+func LoadConfig(v interface{}) error {
+	if err := viper.Unmarshal(v); err != nil {
+		return &ConfigError{Problems: []string{err.Error()}}
+	}
+	validate := validator.New()
+	validate.RegisterTagNameFunc(configKeyName)
+	err := validate.Struct(v)
+	if err == nil {
+		return nil
+	}
+	verrs, ok := err.(validator.ValidationErrors)
+	if !ok {
+		return err
+	}
+	cerr := &ConfigError{}
+	for _, fe := range verrs {
+		cerr.Problems = append(cerr.Problems, describeConfigError(fe))
+	}
+	return cerr
+}
+
+// configKeyName names struct fields after the configuration key they are
+// loaded from.
+func configKeyName(fld reflect.StructField) string {
+	name := strings.SplitN(fld.Tag.Get("mapstructure"), ",", 2)[0]
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return strings.ToLower(fld.Name)
+	}
+	return name
+}
+
+// describeConfigError converts a validation failure into a readable message
+// such as "database is required".
+func describeConfigError(fe validator.FieldError) string {
+	key := fe.Namespace()
+	if idx := strings.Index(key, "."); idx >= 0 {
+		key = key[idx+1:]
+	}
+	switch fe.Tag() {
+	case "required":
+		return fmt.Sprintf("%s is required", key)
+	case "min", "gte":
+		return fmt.Sprintf("%s must be at least %s", key, fe.Param())
+	case "max", "lte":
+		return fmt.Sprintf("%s must be at most %s", key, fe.Param())
+	case "oneof":
+		return fmt.Sprintf("%s must be one of %s", key, fe.Param())
+	}
+	if fe.Param() != "" {
+		return fmt.Sprintf("%s failed %s=%s validation", key, fe.Tag(), fe.Param())
+	}
+	return fmt.Sprintf("%s must be a valid %s", key, fe.Tag())
+}
+
+// effectiveConfig returns every configuration setting sorted by key with its
+// source. Flags are looked up in the given flag set. Secret values are
+// redacted.
+func effectiveConfig(flags *pflag.FlagSet) []ConfigValue {
+	file := viper.New()
+	if used := viper.ConfigFileUsed(); used != "" {
+		readConfigFiles(file, used, viper.GetString("env"))
+	}
+	keys := viper.AllKeys()
+	sort.Strings(keys)
+	values := []ConfigValue{}
+	for _, key := range keys {
+		values = append(values, ConfigValue{
+			Key:    key,
+			Value:  redactConfigValue(key, viper.Get(key)),
+			Source: configSource(key, flags, file),
+		})
+	}
+	return values
+}
+
+// configSource returns where the value of a key comes from: "flag", "env",
+// "file" or "default". The file viper instance holds the settings read from
+// the config files.
+func configSource(key string, flags *pflag.FlagSet, file *viper.Viper) string {
+	if flags != nil {
+		if f := flags.Lookup(key); f != nil && f.Changed {
+			return "flag"
+		}
+	}
+	if _, ok := os.LookupEnv(strings.ToUpper(envKeyReplacer.Replace(key))); ok {
+		return "env"
+	}
+	if file.IsSet(key) {
+		return "file"
+	}
+	return "default"
+}
+
+// redactConfigValue hides values for secret keys and passwords in URLs.
+func redactConfigValue(key string, value interface{}) interface{} {
+	lkey := strings.ToLower(key)
+	for _, secret := range SecretConfigKeys {
+		if strings.Contains(lkey, secret) {
+			if s, ok := value.(string); ok && s == "" {
+				return value
+			}
+			return redacted
+		}
+	}
+	if s, ok := value.(string); ok && strings.Contains(s, "://") {
+		if u, err := url.Parse(s); err == nil && u.User != nil {
+			if _, ok := u.User.Password(); ok {
+				u.User = url.UserPassword(u.User.Username(), redacted)
+				return strings.Replace(u.String(), url.QueryEscape(redacted), redacted, 1)
+			}
+		}
+	}
+	return value
+}
+
+// printConfig prints configuration values as a table or as JSON.
+func printConfig(values []ConfigValue, format string) error {
+	switch format {
+	case "json":
+		b, err := json.MarshalIndent(values, "", "  ")
+		if err != nil {
+			return err
+		}
+		vox.Println(string(b))
+	case "table", "":
+		rows := [][]string{}
+		for _, v := range values {
+			rows = append(rows, []string{v.Key, fmt.Sprint(v.Value), v.Source})
+		}
+		printTable([]string{"KEY", "VALUE", "SOURCE"}, rows)
+	default:
+		return fmt.Errorf("unknown format: %s", format)
+	}
+	return nil
+}
@@ -14,7 +14,6 @@ import (
 	"github.com/tidwall/gjson"
 
 	"github.com/google/uuid"
-	"github.com/spf13/viper"
 )
 
 // Context A request context object
@@ -42,7 +41,7 @@ func NewContext() Context {
 		QueryParams: Params(map[string]string{}),
 		properties:  map[string]interface{}{},
 		Response:    NewResponse(),
-		Env:         viper.GetString("env"),
+		Env:         Config().GetString("env"),
 		RequestID:   strings.Replace(uuid.New().String(), "-", "", -1),
 	}
 }
@@ -114,16 +113,15 @@ func (c *Context) Body() []byte {
 	return c.data
 }
 
-// Fail is used for unrecoverable and internal errors. In a production
-// environment the error is not passed to the client.
-// message.
+// Fail is used for unrecoverable and internal errors. The error is only
+// passed to the client in environments that expose errors.
 func (c *Context) Fail(err error) Response {
 	c.Response.StatusCode = 500
 	c.Response.Data = nil
-	if viper.GetString("env") == PROD {
-		c.Response.Error = errors.New("the request could not be processed")
-	} else {
+	if c.Environment().ExposeErrors {
 		c.Response.Error = err
+	} else {
+		c.Response.Error = errors.New("the request could not be processed")
 	}
 
 	return c.Response
@@ -0,0 +1,300 @@
+package celerity
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/vmihailenco/msgpack"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// newEnvelope builds the default response envelope used by the encoders.
+func newEnvelope(c Context, r Response) JSONResponse {
+	rObj := JSONResponse{
+		RequestID: c.RequestID,
+		Meta:      r.Meta,
+		Data:      r.Data,
+		Success:   r.Error == nil,
+	}
+	if r.Error != nil {
+		rObj.Error = r.Error.Error()
+	}
+	return rObj
+}
+
+// JSONEncoder encodes the response using the JSONResponseAdapter.
+func JSONEncoder(c Context, r Response) ([]byte, error) {
+	return (&JSONResponseAdapter{}).Process(c, r)
+}
+
+// YAMLEncoder encodes the response envelope as YAML.
+func YAMLEncoder(c Context, r Response) ([]byte, error) {
+	return yaml.Marshal(newEnvelope(c, r))
+}
+
+// MsgPackEncoder encodes the response envelope as MessagePack.
+func MsgPackEncoder(c Context, r Response) ([]byte, error) {
+	return msgpack.Marshal(newEnvelope(c, r))
+}
+
+// XMLEncoder encodes the response envelope as XML. Maps are written as
+// elements named after their keys and slices as repeated item elements. Keys
+// that are not valid XML names are written as entry elements with a key
+// attribute.
+func XMLEncoder(c Context, r Response) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	if err := enc.Encode(xmlEnvelope(newEnvelope(c, r))); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+type xmlEnvelope JSONResponse
+
+// MarshalXML writes the envelope as a response element.
+func (env xmlEnvelope) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start = xml.StartElement{Name: xml.Name{Local: "response"}}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	fields := []struct {
+		Name  string
+		Value interface{}
+	}{
+		{"requestId", env.RequestID},
+		{"success", env.Success},
+		{"error", env.Error},
+		{"data", env.Data},
+		{"meta", env.Meta},
+	}
+	for _, f := range fields {
+		if err := encodeXMLValue(e, xmlElement(f.Name), reflect.ValueOf(f.Value)); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// xmlElement creates the element for a map key or field name.
+func xmlElement(name string) xml.StartElement {
+	if isXMLName(name) {
+		return xml.StartElement{Name: xml.Name{Local: name}}
+	}
+	return xml.StartElement{
+		Name: xml.Name{Local: "entry"},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "key"}, Value: name}},
+	}
+}
+
+// isXMLName checks if a string can be used as an element name. Names with a
+// namespace prefix are not allowed.
+func isXMLName(name string) bool {
+	if name == "" || strings.HasPrefix(strings.ToLower(name), "xml") {
+		return false
+	}
+	for i, r := range name {
+		switch {
+		case unicode.IsLetter(r), r == '_':
+		case i > 0 && (unicode.IsDigit(r) || r == '-' || r == '.'):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func encodeXMLValue(e *xml.Encoder, start xml.StartElement, v reflect.Value) error {
+	for v.IsValid() && (v.Kind() == reflect.Interface || v.Kind() == reflect.Ptr) {
+		if v.IsNil() {
+			v = reflect.Value{}
+			break
+		}
+		if v.Kind() == reflect.Ptr && v.Elem().Kind() == reflect.Struct {
+			break
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		if err := e.EncodeToken(start); err != nil {
+			return err
+		}
+		return e.EncodeToken(start.End())
+	}
+
+	switch v.Kind() {
+	case reflect.Map:
+		if err := e.EncodeToken(start); err != nil {
+			return err
+		}
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+		for _, k := range keys {
+			if err := encodeXMLValue(e, xmlElement(fmt.Sprint(k.Interface())), v.MapIndex(k)); err != nil {
+				return err
+			}
+		}
+		return e.EncodeToken(start.End())
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return e.EncodeElement(v.Interface(), start)
+		}
+		if err := e.EncodeToken(start); err != nil {
+			return err
+		}
+		for i := 0; i < v.Len(); i++ {
+			if err := encodeXMLValue(e, xmlElement("item"), v.Index(i)); err != nil {
+				return err
+			}
+		}
+		return e.EncodeToken(start.End())
+	default:
+		return e.EncodeElement(v.Interface(), start)
+	}
+}
+
+// CSVEncoder encodes slice data as CSV. Slices of structs use their field
+// names as the header row, slices of maps use their keys. Responses that do
+// not contain slice data return ErrUnsupportedData.
+func CSVEncoder(c Context, r Response) ([]byte, error) {
+	if r.Error != nil || r.Data == nil {
+		return nil, ErrUnsupportedData
+	}
+	v := reflect.Indirect(reflect.ValueOf(r.Data))
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, ErrUnsupportedData
+	}
+
+	rows := [][]string{}
+	header := csvHeader(v)
+	if len(header) > 0 {
+		rows = append(rows, header)
+	}
+	for i := 0; i < v.Len(); i++ {
+		rows = append(rows, csvRow(v.Index(i), header))
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.WriteAll(rows); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func csvElem(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Interface || v.Kind() == reflect.Ptr) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// csvHeader returns the column names for a slice. Slices of scalars and
+// slices of slices have no header.
+func csvHeader(v reflect.Value) []string {
+	header := []string{}
+	seen := map[string]bool{}
+	for i := 0; i < v.Len(); i++ {
+		item := csvElem(v.Index(i))
+		switch item.Kind() {
+		case reflect.Struct:
+			for _, f := range csvFields(item.Type()) {
+				if !seen[f.Name] {
+					seen[f.Name] = true
+					header = append(header, f.Name)
+				}
+			}
+		case reflect.Map:
+			keys := []string{}
+			for _, k := range item.MapKeys() {
+				if name := fmt.Sprint(k.Interface()); !seen[name] {
+					seen[name] = true
+					keys = append(keys, name)
+				}
+			}
+			sort.Strings(keys)
+			header = append(header, keys...)
+		}
+	}
+	return header
+}
+
+type csvField struct {
+	Name  string
+	Index int
+}
+
+func csvFields(t reflect.Type) []csvField {
+	fields := []csvField{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		name := f.Name
+		if tag := strings.Split(f.Tag.Get("json"), ",")[0]; tag == "-" {
+			continue
+		} else if tag != "" {
+			name = tag
+		}
+		fields = append(fields, csvField{Name: name, Index: i})
+	}
+	return fields
+}
+
+func csvRow(v reflect.Value, header []string) []string {
+	v = csvElem(v)
+	if !v.IsValid() {
+		return make([]string, len(header))
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		values := map[string]string{}
+		for _, f := range csvFields(v.Type()) {
+			values[f.Name] = csvValue(v.Field(f.Index))
+		}
+		row := make([]string, len(header))
+		for i, h := range header {
+			row[i] = values[h]
+		}
+		return row
+	case reflect.Map:
+		values := map[string]string{}
+		for _, k := range v.MapKeys() {
+			values[fmt.Sprint(k.Interface())] = csvValue(v.MapIndex(k))
+		}
+		row := make([]string, len(header))
+		for i, h := range header {
+			row[i] = values[h]
+		}
+		return row
+	case reflect.Slice, reflect.Array:
+		row := make([]string, v.Len())
+		for i := range row {
+			row[i] = csvValue(v.Index(i))
+		}
+		return row
+	default:
+		return []string{csvValue(v)}
+	}
+}
+
+func csvValue(v reflect.Value) string {
+	v = csvElem(v)
+	if !v.IsValid() {
+		return ""
+	}
+	return fmt.Sprint(v.Interface())
+}
@@ -0,0 +1,117 @@
+package celerity
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/spf13/viper"
+)
+
+// Environment describes how the server behaves in a named environment, such as
+// dev, staging or prod.
+type Environment struct {
+	Name string
+	// ExposeErrors passes internal error messages from Fail to clients.
+	ExposeErrors bool
+	// StackTraces includes the stack trace in responses for recovered panics.
+	StackTraces bool
+	// PrettyOutput indents JSON responses when the adapter enables PrettyDev.
+	PrettyOutput bool
+	// CacheTemplates caches parsed view templates.
+	CacheTemplates bool
+}
+
+var (
+	environments = map[string]Environment{
+		DEV: {
+			Name:         DEV,
+			ExposeErrors: true,
+			StackTraces:  true,
+			PrettyOutput: true,
+		},
+		PROD: {
+			Name:           PROD,
+			CacheTemplates: true,
+		},
+	}
+	environmentsMx sync.RWMutex
+)
+
+// RegisterEnvironment adds or replaces a named environment.
+//
+//	celerity.RegisterEnvironment(celerity.Environment{
+//		Name:           "staging",
+//		ExposeErrors:   true,
+//		CacheTemplates: true,
+//	})
+func RegisterEnvironment(env Environment) {
+	environmentsMx.Lock()
+	environments[env.Name] = env
+	environmentsMx.Unlock()
+}
+
+// LookupEnvironment returns the environment registered with a name.
+// Environments that have not been registered use the same traits as PROD, so
+// errors are never exposed by accident. An empty name is treated as DEV.
+func LookupEnvironment(name string) Environment {
+	if name == "" {
+		name = DEV
+	}
+	environmentsMx.RLock()
+	defer environmentsMx.RUnlock()
+	if env, ok := environments[name]; ok {
+		return env
+	}
+	env := environments[PROD]
+	env.Name = name
+	return env
+}
+
+// CurrentEnvironment returns the environment selected with the env flag,
+// environment variable or config setting.
+func CurrentEnvironment() Environment {
+	return LookupEnvironment(Config().GetString("env"))
+}
+
+// Environment returns the environment the request is being handled in.
+func (c *Context) Environment() Environment {
+	return LookupEnvironment(c.Env)
+}
+
+// environmentConfigFile returns the path of the config file for an
+// environment, such as config.staging.yaml for config.yaml.
+func environmentConfigFile(base, env string) string {
+	ext := filepath.Ext(base)
+	return strings.TrimSuffix(base, ext) + "." + env + ext
+}
+
+// readConfigFiles reads a config file into v and merges the config file for
+// the environment over it. A missing base file is not an error; the config
+// file for the environment is still merged.
+func readConfigFiles(v *viper.Viper, base, env string) error {
+	v.SetConfigFile(base)
+	if err := v.ReadInConfig(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	_, err := mergeEnvironmentConfig(v, base, env)
+	return err
+}
+
+// mergeEnvironmentConfig merges the config file for the environment over the
+// configuration already read from base. It returns the path of the merged file
+// or an empty string if the environment has no config file.
+func mergeEnvironmentConfig(v *viper.Viper, base, env string) (string, error) {
+	if env == "" {
+		return "", nil
+	}
+	envFile := environmentConfigFile(base, env)
+	if _, err := os.Stat(envFile); err != nil {
+		return "", nil
+	}
+	v.SetConfigFile(envFile)
+	defer v.SetConfigFile(base)
+	return envFile, v.MergeInConfig()
+}
@@ -0,0 +1,129 @@
+package celerity
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestLookupEnvironment(t *testing.T) {
+	if env := LookupEnvironment(DEV); !env.ExposeErrors || !env.StackTraces {
+		t.Errorf("dev traits were %v", env)
+	}
+	if env := LookupEnvironment(""); env.Name != DEV {
+		t.Errorf("empty environment was %v", env)
+	}
+	env := LookupEnvironment("qa")
+	if env.Name != "qa" || env.ExposeErrors || env.StackTraces || !env.CacheTemplates {
+		t.Errorf("unknown environment traits were %v", env)
+	}
+}
+
+func TestEnvironmentTraits(t *testing.T) {
+	RegisterEnvironment(Environment{Name: "staging", ExposeErrors: true})
+	defer func() {
+		environmentsMx.Lock()
+		delete(environments, "staging")
+		environmentsMx.Unlock()
+	}()
+
+	t.Run("fail", func(t *testing.T) {
+		for env, exposed := range map[string]bool{"staging": true, "qa": false, PROD: false} {
+			c := NewContext()
+			c.Env = env
+			r := c.Fail(errors.New("some error"))
+			if (r.Error.Error() == "some error") != exposed {
+				t.Errorf("%s: error was %s", env, r.Error.Error())
+			}
+		}
+	})
+
+	t.Run("panic recovery", func(t *testing.T) {
+		s := newScope("/")
+		s.GET("/foo", func(c Context) Response {
+			panic("uh oh")
+		})
+		req, _ := http.NewRequest("GET", "http://example.com/foo", nil)
+		for env, traces := range map[string]bool{DEV: true, "staging": false} {
+			c := RequestContext(req)
+			c.Env = env
+			r := s.Handle(c)
+			if _, ok := r.Data.([]string); ok != traces {
+				t.Errorf("%s: stack trace was %v", env, r.Data)
+			}
+		}
+	})
+}
+
+func TestEnvironmentConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "celerity-env")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer os.RemoveAll(dir)
+	base := filepath.Join(dir, "config.yaml")
+	ioutil.WriteFile(base, []byte("database: localhost\nworkers: 2\nregion: eu\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "config.staging.yaml"), []byte("database: staging-db\nworkers: 4\n"), 0644)
+	os.Setenv("WORKERS", "8")
+	defer os.Unsetenv("WORKERS")
+
+	v := viper.New()
+	v.AutomaticEnv()
+	if err := readConfigFiles(v, base, "staging"); err != nil {
+		t.Fatal(err.Error())
+	}
+	if s := v.GetString("database"); s != "staging-db" {
+		t.Errorf("environment file was not merged: %s", s)
+	}
+	if s := v.GetString("region"); s != "eu" {
+		t.Errorf("base setting was lost: %s", s)
+	}
+	if n := v.GetInt("workers"); n != 8 {
+		t.Errorf("environment variable did not take precedence: %d", n)
+	}
+	if s := v.ConfigFileUsed(); s != base {
+		t.Errorf("config file was %s", s)
+	}
+
+	v = viper.New()
+	if err := readConfigFiles(v, base, "qa"); err != nil {
+		t.Fatal(err.Error())
+	}
+	if s := v.GetString("database"); s != "localhost" {
+		t.Errorf("missing environment file changed settings: %s", s)
+	}
+}
+
+func TestEnvironmentConfigWithoutBase(t *testing.T) {
+	defer viper.Reset()
+	dir, err := ioutil.TempDir("", "celerity-env")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "config.staging.yaml"), []byte("database: staging-db\n"), 0644)
+
+	v := viper.New()
+	if err := readConfigFiles(v, filepath.Join(dir, "config.yaml"), "staging"); err != nil {
+		t.Fatal(err.Error())
+	}
+	if s := v.GetString("database"); s != "staging-db" {
+		t.Errorf("environment file was not merged: %s", s)
+	}
+
+	wd, _ := os.Getwd()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err.Error())
+	}
+	defer os.Chdir(wd)
+	viper.Set("env", "staging")
+	readConfig()
+	if s := viper.GetString("database"); s != "staging-db" {
+		t.Errorf("environment file was not read: %s", s)
+	}
+}
@@ -0,0 +1,205 @@
+package celerity
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// HealthCheck reports the health of a dependency. It should return an error
+// when the dependency is unavailable and return promptly once the context is
+// done.
+type HealthCheck func(ctx context.Context) error
+
+// Health aggregates the health checks of a server and serves them on the
+// /healthz, /readyz and /livez endpoints.
+//
+//	server.Health().Add("database", func(ctx context.Context) error {
+//		return db.PingContext(ctx)
+//	})
+//
+// Readiness checks decide whether the server should receive traffic and are
+// reported by /readyz. Liveness checks decide whether the process should be
+// restarted and are reported by /livez. /healthz reports both.
+type Health struct {
+	// Timeout bounds how long each check may run. It defaults to five seconds.
+	Timeout time.Duration
+	// DrainDelay is how long Shutdown keeps serving requests after readiness
+	// starts failing, giving load balancers time to stop sending traffic.
+	DrainDelay time.Duration
+	server     *Server
+	readiness  map[string]HealthCheck
+	liveness   map[string]HealthCheck
+	mx         sync.RWMutex
+}
+
+// CheckResult is the outcome of a single health check.
+type CheckResult struct {
+	Status  string `json:"status"`
+	Latency string `json:"latency"`
+	Error   string `json:"error,omitempty"`
+}
+
+// HealthReport is the body returned by the health endpoints.
+type HealthReport struct {
+	Status       string                 `json:"status"`
+	ShuttingDown bool                   `json:"shuttingDown"`
+	Checks       map[string]CheckResult `json:"checks"`
+	Jobs         JobStats               `json:"jobs"`
+	Channels     map[string]int         `json:"channels"`
+}
+
+const (
+	healthPass = "pass"
+	healthFail = "fail"
+)
+
+// Health returns the health checks for the server. The first call registers
+// the /healthz, /readyz and /livez endpoints. They are served ahead of the
+// router, so middleware added with Pre or Use, such as authentication, does not
+// apply to them.
+func (s *Server) Health() *Health {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if s.health != nil {
+		return s.health
+	}
+	h := &Health{
+		Timeout:   5 * time.Second,
+		server:    s,
+		readiness: map[string]HealthCheck{},
+		liveness:  map[string]HealthCheck{},
+	}
+	s.health = h
+	router := NewRouter()
+	router.Root.server = s
+	router.Root.GET("/healthz", h.handler(true, true))
+	router.Root.GET("/readyz", h.handler(true, false))
+	router.Root.GET("/livez", h.handler(false, true))
+	s.healthRouter.Store(router)
+	return h
+}
+
+// Add registers a readiness check.
+func (h *Health) Add(name string, check HealthCheck) {
+	h.mx.Lock()
+	h.readiness[name] = check
+	h.mx.Unlock()
+}
+
+// AddLiveness registers a liveness check.
+func (h *Health) AddLiveness(name string, check HealthCheck) {
+	h.mx.Lock()
+	h.liveness[name] = check
+	h.mx.Unlock()
+}
+
+// Check runs the readiness and liveness checks concurrently and builds a
+// report. Readiness fails while the server is shutting down.
+func (h *Health) Check(ctx context.Context, readiness, liveness bool) HealthReport {
+	checks := map[string]HealthCheck{}
+	h.mx.RLock()
+	if readiness {
+		for name, check := range h.readiness {
+			checks[name] = check
+		}
+	}
+	if liveness {
+		for name, check := range h.liveness {
+			checks[name] = check
+		}
+	}
+	h.mx.RUnlock()
+
+	report := HealthReport{
+		Status:       healthPass,
+		ShuttingDown: h.server.isShuttingDown(),
+		Checks:       map[string]CheckResult{},
+		Jobs:         transport.JobManager.Pool.Stats(),
+		Channels:     map[string]int{},
+	}
+	for name, ch := range h.server.Channels {
+		report.Channels[name] = ch.ClientCount()
+	}
+
+	var wg sync.WaitGroup
+	var mx sync.Mutex
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check HealthCheck) {
+			defer wg.Done()
+			result := h.run(ctx, check)
+			mx.Lock()
+			report.Checks[name] = result
+			mx.Unlock()
+		}(name, check)
+	}
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		if result.Status != healthPass {
+			report.Status = healthFail
+		}
+	}
+	if readiness && report.ShuttingDown {
+		report.Status = healthFail
+	}
+	return report
+}
+
+// run executes a single check bounded by the health timeout.
+func (h *Health) run(ctx context.Context, check HealthCheck) CheckResult {
+	timeout := h.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	errs := make(chan error, 1)
+	go func() {
+		errs <- check(ctx)
+	}()
+	var err error
+	select {
+	case err = <-errs:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	result := CheckResult{
+		Status:  healthPass,
+		Latency: time.Since(start).String(),
+	}
+	if err != nil {
+		result.Status = healthFail
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// handler serves a health report as JSON. Failing reports use status 503.
+func (h *Health) handler(readiness, liveness bool) RouteHandler {
+	return func(c Context) Response {
+		report := h.Check(c.Request.Context(), readiness, liveness)
+		b, err := json.Marshal(report)
+		if err != nil {
+			return c.Fail(err)
+		}
+		c.Response.Header.Set("Content-Type", "application/json")
+		c.Response.Header.Set("Cache-Control", "no-cache")
+		r := c.Raw(b)
+		if report.Status != healthPass {
+			return r.Status(http.StatusServiceUnavailable)
+		}
+		return r
+	}
+}
+
+// isShuttingDown checks if Shutdown has been called.
+func (s *Server) isShuttingDown() bool {
+	return atomic.LoadInt32(&s.shuttingDown) == 1
+}
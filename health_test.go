@@ -0,0 +1,180 @@
+package celerity
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func healthRequest(t *testing.T, server *Server, path string) (int, HealthReport) {
+	req := httptest.NewRequest("GET", path, nil)
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("content type was %s", ct)
+	}
+	report := HealthReport{}
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatalf("invalid report: %s", w.Body.String())
+	}
+	return w.Code, report
+}
+
+func TestHealth(t *testing.T) {
+	server := New()
+	server.Channel("events", "/events", func(client *SocketClient, e ChannelEvent) {})
+	health := server.Health()
+	health.Timeout = 50 * time.Millisecond
+	var dbDown int32
+	health.Add("database", func(ctx context.Context) error {
+		if atomic.LoadInt32(&dbDown) == 1 {
+			return errors.New("connection refused")
+		}
+		return nil
+	})
+	health.AddLiveness("deadlock", func(ctx context.Context) error {
+		return nil
+	})
+
+	t.Run("passing", func(t *testing.T) {
+		code, report := healthRequest(t, server, "/healthz")
+		if code != http.StatusOK {
+			t.Errorf("status was %d", code)
+		}
+		if report.Status != "pass" {
+			t.Errorf("report status was %s", report.Status)
+		}
+		if len(report.Checks) != 2 {
+			t.Errorf("checks were %v", report.Checks)
+		}
+		if report.Checks["database"].Latency == "" {
+			t.Error("latency not reported")
+		}
+		if n, ok := report.Channels["events"]; !ok || n != 0 {
+			t.Errorf("channels were %v", report.Channels)
+		}
+		if report.Jobs.Workers == 0 {
+			t.Errorf("jobs were %v", report.Jobs)
+		}
+	})
+
+	t.Run("failing readiness", func(t *testing.T) {
+		atomic.StoreInt32(&dbDown, 1)
+		defer atomic.StoreInt32(&dbDown, 0)
+		code, report := healthRequest(t, server, "/readyz")
+		if code != http.StatusServiceUnavailable {
+			t.Errorf("status was %d", code)
+		}
+		if r := report.Checks["database"]; r.Status != "fail" || r.Error != "connection refused" {
+			t.Errorf("check was %v", r)
+		}
+		if _, ok := report.Checks["deadlock"]; ok {
+			t.Error("liveness check reported by readyz")
+		}
+		code, report = healthRequest(t, server, "/livez")
+		if code != http.StatusOK {
+			t.Errorf("livez status was %d", code)
+		}
+		if _, ok := report.Checks["database"]; ok {
+			t.Error("readiness check reported by livez")
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		health.Add("slow", func(ctx context.Context) error {
+			time.Sleep(time.Second)
+			return nil
+		})
+		defer func() {
+			health.mx.Lock()
+			delete(health.readiness, "slow")
+			health.mx.Unlock()
+		}()
+		start := time.Now()
+		code, report := healthRequest(t, server, "/readyz")
+		if time.Since(start) > 500*time.Millisecond {
+			t.Error("check was not bounded by the timeout")
+		}
+		if code != http.StatusServiceUnavailable {
+			t.Errorf("status was %d", code)
+		}
+		if r := report.Checks["slow"]; r.Error != context.DeadlineExceeded.Error() {
+			t.Errorf("check was %v", r)
+		}
+	})
+}
+
+func TestHealthBypassesMiddleware(t *testing.T) {
+	server := New()
+	server.Pre(func(next RouteHandler) RouteHandler {
+		return func(c Context) Response {
+			if c.Header("Authorization") != "secret" {
+				return c.Error(http.StatusUnauthorized, errors.New("unauthorized"))
+			}
+			return next(c)
+		}
+	})
+	server.Use(func(next RouteHandler) RouteHandler {
+		return func(c Context) Response {
+			if c.Header("Authorization") != "secret" {
+				return c.Error(http.StatusUnauthorized, errors.New("unauthorized"))
+			}
+			return next(c)
+		}
+	})
+	server.GET("/private", func(c Context) Response {
+		return c.R("private")
+	})
+	server.Health()
+
+	for _, path := range []string{"/healthz", "/readyz", "/livez"} {
+		if code, report := healthRequest(t, server, path); code != http.StatusOK || report.Status != "pass" {
+			t.Errorf("%s returned %d", path, code)
+		}
+	}
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest("GET", "/private", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("middleware did not apply to other routes: %d", w.Code)
+	}
+}
+
+func TestHealthShutdown(t *testing.T) {
+	server := New()
+	health := server.Health()
+	health.DrainDelay = 200 * time.Millisecond
+	addr := freeAddr(t)
+	go server.Start(addr)
+	waitForServer(t, addr)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- server.Shutdown(context.Background())
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	res, err := http.Get("http://" + addr + "/readyz")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("readyz status during shutdown was %d", res.StatusCode)
+	}
+	res, err = http.Get("http://" + addr + "/livez")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("livez status during shutdown was %d", res.StatusCode)
+	}
+	if err := <-done; err != nil {
+		t.Error(err.Error())
+	}
+}
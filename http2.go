@@ -0,0 +1,55 @@
+package celerity
+
+import (
+	"crypto/tls"
+	"net/http"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// newHTTPServer creates an http.Server for the handler and stores it so it can
+// be stopped with Shutdown. The TLS config may be nil for plain connections.
+//
+// HTTP/2 is negotiated automatically for TLS connections unless DisableHTTP2
+// is set. When H2C is set plain connections accept cleartext HTTP/2, either
+// through an h2c upgrade or with prior knowledge. The same HTTP/2 server
+// handles both, so Shutdown also drains cleartext HTTP/2 connections.
+func (s *Server) newHTTPServer(handler http.Handler, tlsConfig *tls.Config) (*http.Server, error) {
+	hs := &http.Server{Handler: handler, TLSConfig: tlsConfig}
+	s.Limits.apply(hs)
+	if s.DisableHTTP2 {
+		hs.TLSNextProto = map[string]func(*http.Server, *tls.Conn, http.Handler){}
+	} else {
+		h2s := &http2.Server{}
+		if err := http2.ConfigureServer(hs, h2s); err != nil {
+			return nil, err
+		}
+		if s.H2C {
+			hs.Handler = h2c.NewHandler(handler, h2s)
+		}
+	}
+	s.mx.Lock()
+	s.httpServers = append(s.httpServers, hs)
+	s.mx.Unlock()
+	return hs, nil
+}
+
+// Protocol returns the protocol of the request, such as "HTTP/1.1" or
+// "HTTP/2.0". It returns an empty string if the context has no request.
+func (c *Context) Protocol() string {
+	if c.Request == nil {
+		return ""
+	}
+	return c.Request.Proto
+}
+
+// IsHTTP2 checks if the request was made using HTTP/2, over TLS or h2c.
+func (c *Context) IsHTTP2() bool {
+	return c.Request != nil && c.Request.ProtoMajor == 2
+}
+
+// IsTLS checks if the request was made over a TLS connection.
+func (c *Context) IsTLS() bool {
+	return c.Request != nil && c.Request.TLS != nil
+}
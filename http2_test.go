@@ -0,0 +1,144 @@
+package celerity
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+func TestHTTP2(t *testing.T) {
+	protoHandler := func(c Context) Response {
+		if c.IsHTTP2() != (c.Request.ProtoMajor == 2) {
+			return c.Raw([]byte("mismatch"))
+		}
+		proto := c.Protocol()
+		if c.IsTLS() {
+			proto += " tls"
+		}
+		return c.Raw([]byte(proto))
+	}
+	get := func(client *http.Client, url string) string {
+		res, err := client.Get(url)
+		if err != nil {
+			t.Fatalf("Error requesting url: %s", err.Error())
+		}
+		defer res.Body.Close()
+		b, _ := ioutil.ReadAll(res.Body)
+		return string(b)
+	}
+	h2cClient := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLS: func(network, addr string, cfg *tls.Config) (net.Conn, error) {
+			return net.Dial(network, addr)
+		},
+	}}
+
+	t.Run("h2c prior knowledge", func(t *testing.T) {
+		server := New()
+		server.H2C = true
+		server.GET("/proto", protoHandler)
+		addr := freeAddr(t)
+		go server.Start(addr)
+		defer server.Shutdown(context.Background())
+		waitForServer(t, addr)
+
+		if v := get(h2cClient, "http://"+addr+"/proto"); v != "HTTP/2.0" {
+			t.Errorf("protocol was %s", v)
+		}
+		if v := get(http.DefaultClient, "http://"+addr+"/proto"); v != "HTTP/1.1" {
+			t.Errorf("protocol was %s", v)
+		}
+	})
+
+	t.Run("h2c shutdown", func(t *testing.T) {
+		server := New()
+		server.H2C = true
+		server.GET("/slow", func(c Context) Response {
+			time.Sleep(200 * time.Millisecond)
+			return c.Raw([]byte(c.Protocol()))
+		})
+		addr := freeAddr(t)
+		go server.Start(addr)
+		waitForServer(t, addr)
+
+		body := make(chan string, 1)
+		go func() {
+			body <- get(h2cClient, "http://"+addr+"/slow")
+		}()
+		time.Sleep(50 * time.Millisecond)
+		if err := server.Shutdown(context.Background()); err != nil {
+			t.Fatal(err.Error())
+		}
+		if v := <-body; v != "HTTP/2.0" {
+			t.Errorf("in-flight h2c request returned %s", v)
+		}
+	})
+
+	t.Run("h2c disabled", func(t *testing.T) {
+		server := New()
+		server.GET("/proto", protoHandler)
+		addr := freeAddr(t)
+		go server.Start(addr)
+		defer server.Shutdown(context.Background())
+		waitForServer(t, addr)
+
+		if _, err := h2cClient.Get("http://" + addr + "/proto"); err == nil {
+			t.Error("cleartext HTTP/2 was accepted")
+		}
+	})
+
+	t.Run("no request", func(t *testing.T) {
+		c := NewContext()
+		if c.Protocol() != "" || c.IsHTTP2() || c.IsTLS() {
+			t.Error("context without a request reported a protocol")
+		}
+	})
+
+	t.Run("tls", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "celerity-h2")
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		defer os.RemoveAll(dir)
+		ca, caKey, caPEM, _ := testCert(t, "ca", 1, nil, nil)
+		_, _, certPEM, keyPEM := testCert(t, "server", 2, ca, caKey)
+		certFile := filepath.Join(dir, "cert.pem")
+		keyFile := filepath.Join(dir, "key.pem")
+		ioutil.WriteFile(certFile, certPEM, 0600)
+		ioutil.WriteFile(keyFile, keyPEM, 0600)
+		roots := x509.NewCertPool()
+		roots.AppendCertsFromPEM(caPEM)
+		config := TLSConfig{CertFile: certFile, KeyFile: keyFile}
+
+		for _, disabled := range []bool{false, true} {
+			server := New()
+			server.DisableHTTP2 = disabled
+			server.GET("/proto", protoHandler)
+			addr := freeAddr(t)
+			go server.StartTLS(addr, config)
+			waitForServer(t, addr)
+
+			client := &http.Client{Transport: &http.Transport{
+				TLSClientConfig:   &tls.Config{RootCAs: roots},
+				ForceAttemptHTTP2: true,
+			}}
+			expected := "HTTP/2.0 tls"
+			if disabled {
+				expected = "HTTP/1.1 tls"
+			}
+			if v := get(client, "https://"+addr+"/proto"); v != expected {
+				t.Errorf("protocol was %s", v)
+			}
+			server.Shutdown(context.Background())
+		}
+	})
+}
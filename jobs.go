@@ -2,8 +2,10 @@ package celerity
 
 import (
 	"bytes"
+	"context"
 	"encoding/gob"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -116,6 +118,7 @@ type jobPool struct {
 	PoolSize    int
 	jobs        chan Job
 	waitgroup   sync.WaitGroup
+	pending     int64
 }
 
 // NewJobPool creates a new JobPool based on the passed configuration.
@@ -133,18 +136,53 @@ func (jp *jobPool) WaitForAll() {
 	jp.waitgroup.Wait()
 }
 
+// WaitForJobs blocks until all queued jobs have finished running or the
+// context is done.
+func WaitForJobs(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		transport.JobManager.Pool.WaitForAll()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // Worker executes a pending job
 func (jp *jobPool) worker() {
 	for job := range jp.jobs {
 		job.Run()
+		atomic.AddInt64(&jp.pending, -1)
 		jp.waitgroup.Done()
 	}
 }
 
 // Queue pends a job for execution
 func (jp *jobPool) Queue(job Job) {
-	jp.jobs <- job
 	jp.waitgroup.Add(1)
+	atomic.AddInt64(&jp.pending, 1)
+	jp.jobs <- job
+}
+
+// JobStats reports the state of the job pool.
+type JobStats struct {
+	Workers int `json:"workers"`
+	Queued  int `json:"queued"`
+	Pending int `json:"pending"`
+}
+
+// Stats returns the number of workers, the jobs waiting for a worker and the
+// jobs that have not finished running.
+func (jp *jobPool) Stats() JobStats {
+	return JobStats{
+		Workers: jp.WorkerCount,
+		Queued:  len(jp.jobs),
+		Pending: int(atomic.LoadInt64(&jp.pending)),
+	}
 }
 
 // Start starts the workers for the pool
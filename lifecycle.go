@@ -0,0 +1,76 @@
+package celerity
+
+import (
+	"context"
+	"time"
+)
+
+// defaultStartTimeout bounds the start and ready hooks when StartTimeout is
+// not set.
+const defaultStartTimeout = 30 * time.Second
+
+// OnStart registers a function that is called before the server begins
+// accepting connections. Hooks run in the order they were registered. If a
+// hook returns an error the remaining hooks are skipped and startup is aborted
+// with that error.
+//
+//	server.OnStart(func(ctx context.Context) error {
+//		return db.PingContext(ctx)
+//	})
+func (s *Server) OnStart(f func(context.Context) error) {
+	s.mx.Lock()
+	s.startHooks = append(s.startHooks, f)
+	s.mx.Unlock()
+}
+
+// OnReady registers a function that is called once the listeners are bound
+// and the server is accepting connections. Hooks run in the order they were
+// registered. If a hook returns an error the server is stopped and the error
+// is returned from Start.
+func (s *Server) OnReady(f func(context.Context) error) {
+	s.mx.Lock()
+	s.readyHooks = append(s.readyHooks, f)
+	s.mx.Unlock()
+}
+
+// runStartHooks runs the start hooks. They only run once per server, so
+// callers that bind listeners after the hooks can safely call Serve.
+func (s *Server) runStartHooks() error {
+	s.mx.Lock()
+	if s.started {
+		s.mx.Unlock()
+		return nil
+	}
+	s.started = true
+	hooks := s.startHooks
+	s.mx.Unlock()
+	return s.runHooks(hooks)
+}
+
+// runReadyHooks runs the ready hooks.
+func (s *Server) runReadyHooks() error {
+	s.mx.Lock()
+	hooks := s.readyHooks
+	s.mx.Unlock()
+	return s.runHooks(hooks)
+}
+
+// runHooks runs hooks in order, stopping at the first error. The hooks share a
+// context bounded by StartTimeout.
+func (s *Server) runHooks(hooks []func(context.Context) error) error {
+	if len(hooks) == 0 {
+		return nil
+	}
+	timeout := s.StartTimeout
+	if timeout <= 0 {
+		timeout = defaultStartTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	for _, f := range hooks {
+		if err := f(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package celerity
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestLifecycleHooks(t *testing.T) {
+	server := New()
+	addr := freeAddr(t)
+	calls := []string{}
+	server.OnStart(func(ctx context.Context) error {
+		if _, ok := ctx.Deadline(); !ok {
+			t.Error("start hook context has no deadline")
+		}
+		if _, err := net.Dial("tcp", addr); err == nil {
+			t.Error("address bound before start hooks")
+		}
+		calls = append(calls, "start1")
+		return nil
+	})
+	server.OnStart(func(ctx context.Context) error {
+		calls = append(calls, "start2")
+		return nil
+	})
+	ready := make(chan struct{})
+	server.OnReady(func(ctx context.Context) error {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Error("listener not bound in ready hook")
+		} else {
+			conn.Close()
+		}
+		calls = append(calls, "ready")
+		close(ready)
+		return nil
+	})
+
+	served := make(chan error, 1)
+	go func() {
+		served <- server.Start(addr)
+	}()
+	select {
+	case <-ready:
+	case err := <-served:
+		t.Fatalf("server stopped: %v", err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("ready hooks did not run")
+	}
+	server.Shutdown(context.Background())
+	if err := <-served; err != nil {
+		t.Error(err.Error())
+	}
+	if len(calls) != 3 || calls[0] != "start1" || calls[1] != "start2" || calls[2] != "ready" {
+		t.Errorf("hooks were %v", calls)
+	}
+}
+
+func TestLifecycleHookErrors(t *testing.T) {
+	t.Run("start", func(t *testing.T) {
+		server := New()
+		addr := freeAddr(t)
+		ran := false
+		server.OnStart(func(ctx context.Context) error {
+			return errors.New("migration failed")
+		})
+		server.OnStart(func(ctx context.Context) error {
+			ran = true
+			return nil
+		})
+		server.OnReady(func(ctx context.Context) error {
+			ran = true
+			return nil
+		})
+		if err := server.Start(addr); err == nil || err.Error() != "migration failed" {
+			t.Errorf("start returned %v", err)
+		}
+		if ran {
+			t.Error("hooks ran after a failed start hook")
+		}
+		if _, err := net.Dial("tcp", addr); err == nil {
+			t.Error("address bound after a failed start hook")
+		}
+	})
+
+	t.Run("ready", func(t *testing.T) {
+		server := New()
+		addr := freeAddr(t)
+		server.OnReady(func(ctx context.Context) error {
+			return errors.New("registration failed")
+		})
+		done := make(chan error, 1)
+		go func() {
+			done <- server.Start(addr)
+		}()
+		select {
+		case err := <-done:
+			if err == nil || err.Error() != "registration failed" {
+				t.Errorf("start returned %v", err)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("server did not stop after a failed ready hook")
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		server := New()
+		server.StartTimeout = 20 * time.Millisecond
+		server.OnStart(func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+		if err := server.Start(freeAddr(t)); err != context.DeadlineExceeded {
+			t.Errorf("start returned %v", err)
+		}
+	})
+}
@@ -0,0 +1,91 @@
+package celerity
+
+import (
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/spf13/viper"
+	"golang.org/x/net/netutil"
+)
+
+// ServerLimits configures the timeouts and connection limits of the
+// underlying http.Server. Zero values disable a limit. New servers start with
+// the limits from NewServerLimits.
+//
+// The run command uses the server's limits as defaults that the command line
+// flags and the config file override, so setting a limit to zero in either
+// disables it.
+//
+// ReadTimeout and WriteTimeout also apply to streaming responses and should be
+// left disabled when serving long polling requests.
+type ServerLimits struct {
+	// ReadTimeout is the maximum duration for reading an entire request.
+	ReadTimeout time.Duration
+	// ReadHeaderTimeout is the maximum duration for reading request headers.
+	// It protects against clients that send headers slowly.
+	ReadHeaderTimeout time.Duration
+	// WriteTimeout is the maximum duration for writing a response.
+	WriteTimeout time.Duration
+	// IdleTimeout is how long keep-alive connections wait for the next
+	// request.
+	IdleTimeout time.Duration
+	// MaxHeaderBytes is the maximum size of request headers.
+	MaxHeaderBytes int
+	// MaxConnections is the maximum number of concurrent connections accepted
+	// on each listener.
+	MaxConnections int
+}
+
+// NewServerLimits creates the default limits. Request headers must arrive
+// within 10 seconds and idle keep-alive connections are closed after two
+// minutes.
+func NewServerLimits() ServerLimits {
+	return ServerLimits{
+		ReadHeaderTimeout: 10 * time.Second,
+		IdleTimeout:       2 * time.Minute,
+		MaxHeaderBytes:    http.DefaultMaxHeaderBytes,
+	}
+}
+
+// limitsFromViper reads the server limits. The given limits are registered as
+// defaults, so only values set by a flag, the environment or the config file
+// replace them.
+func limitsFromViper(v *viper.Viper, defaults ServerLimits) ServerLimits {
+	v.SetDefault("read-timeout", defaults.ReadTimeout)
+	v.SetDefault("read-header-timeout", defaults.ReadHeaderTimeout)
+	v.SetDefault("write-timeout", defaults.WriteTimeout)
+	v.SetDefault("idle-timeout", defaults.IdleTimeout)
+	v.SetDefault("max-header-bytes", defaults.MaxHeaderBytes)
+	v.SetDefault("max-connections", defaults.MaxConnections)
+	return ServerLimits{
+		ReadTimeout:       v.GetDuration("read-timeout"),
+		ReadHeaderTimeout: v.GetDuration("read-header-timeout"),
+		WriteTimeout:      v.GetDuration("write-timeout"),
+		IdleTimeout:       v.GetDuration("idle-timeout"),
+		MaxHeaderBytes:    v.GetInt("max-header-bytes"),
+		MaxConnections:    v.GetInt("max-connections"),
+	}
+}
+
+// apply sets the timeouts on an http.Server.
+func (l ServerLimits) apply(hs *http.Server) {
+	hs.ReadTimeout = l.ReadTimeout
+	hs.ReadHeaderTimeout = l.ReadHeaderTimeout
+	hs.WriteTimeout = l.WriteTimeout
+	hs.IdleTimeout = l.IdleTimeout
+	hs.MaxHeaderBytes = l.MaxHeaderBytes
+}
+
+// limitListeners restricts the number of concurrent connections on each
+// listener.
+func (l ServerLimits) limitListeners(listeners []net.Listener) []net.Listener {
+	if l.MaxConnections <= 0 {
+		return listeners
+	}
+	limited := make([]net.Listener, len(listeners))
+	for i, ln := range listeners {
+		limited[i] = netutil.LimitListener(ln, l.MaxConnections)
+	}
+	return limited
+}
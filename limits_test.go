@@ -0,0 +1,134 @@
+package celerity
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+func TestServerLimits(t *testing.T) {
+	server := New()
+	server.Limits = ServerLimits{
+		ReadHeaderTimeout: 100 * time.Millisecond,
+		MaxHeaderBytes:    1024,
+		MaxConnections:    1,
+	}
+	server.GET("/ok", func(c Context) Response {
+		return c.Raw([]byte("ok"))
+	})
+	addr := freeAddr(t)
+	go server.Start(addr)
+	defer server.Shutdown(context.Background())
+	waitForServer(t, addr)
+	// waitForServer holds a connection slot until the server notices it closed.
+	time.Sleep(50 * time.Millisecond)
+
+	t.Run("read header timeout", func(t *testing.T) {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		defer conn.Close()
+		fmt.Fprint(conn, "GET /ok HTTP/1.1\r\nHost: localhost\r\n")
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		start := time.Now()
+		buf := make([]byte, 1)
+		if _, err := conn.Read(buf); err == nil {
+			t.Error("slow client received a response")
+		}
+		if time.Since(start) > time.Second {
+			t.Error("connection was not closed by the server")
+		}
+	})
+
+	t.Run("max header bytes", func(t *testing.T) {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		defer conn.Close()
+		fmt.Fprintf(conn, "GET /ok HTTP/1.1\r\nHost: localhost\r\nX-Large: %s\r\n\r\n",
+			strings.Repeat("a", 8192))
+		res, err := http.ReadResponse(bufio.NewReader(conn), nil)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if res.StatusCode != http.StatusRequestHeaderFieldsTooLarge {
+			t.Errorf("status code was %d", res.StatusCode)
+		}
+	})
+
+	t.Run("max connections", func(t *testing.T) {
+		time.Sleep(50 * time.Millisecond)
+		held, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		fmt.Fprint(held, "GET /ok HTTP/1.1\r\nHost: localhost\r\n\r\n")
+		if _, err := http.ReadResponse(bufio.NewReader(held), nil); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		client := &http.Client{
+			Timeout:   200 * time.Millisecond,
+			Transport: &http.Transport{DisableKeepAlives: true},
+		}
+		if _, err := client.Get("http://" + addr + "/ok"); err == nil {
+			t.Error("connection over the limit was served")
+		}
+
+		held.Close()
+		client.Timeout = 2 * time.Second
+		res, err := client.Get("http://" + addr + "/ok")
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		res.Body.Close()
+	})
+}
+
+func TestLimitsFromViper(t *testing.T) {
+	v := viper.New()
+	v.SetConfigType("yaml")
+	v.ReadConfig(strings.NewReader("write-timeout: 0\nidle-timeout: 30s\n"))
+	flags := pflag.NewFlagSet("run", pflag.ContinueOnError)
+	flags.Int("max-connections", 0, "")
+	flags.Duration("read-timeout", time.Minute, "")
+	v.BindPFlag("max-connections", flags.Lookup("max-connections"))
+	v.BindPFlag("read-timeout", flags.Lookup("read-timeout"))
+	flags.Parse([]string{"--max-connections=0"})
+
+	code := NewServerLimits()
+	code.ReadHeaderTimeout = 0
+	code.WriteTimeout = time.Second
+	code.MaxConnections = 50
+	code.ReadTimeout = 5 * time.Second
+	limits := limitsFromViper(v, code)
+
+	if limits.WriteTimeout != 0 {
+		t.Errorf("config did not disable write timeout: %s", limits.WriteTimeout)
+	}
+	if limits.IdleTimeout != 30*time.Second {
+		t.Errorf("config did not override idle timeout: %s", limits.IdleTimeout)
+	}
+	if limits.MaxConnections != 0 {
+		t.Errorf("flag did not disable max connections: %d", limits.MaxConnections)
+	}
+	if limits.ReadHeaderTimeout != 0 {
+		t.Errorf("code could not disable read header timeout: %s", limits.ReadHeaderTimeout)
+	}
+	if limits.ReadTimeout != 5*time.Second {
+		t.Errorf("unchanged flag default replaced the code value: %s", limits.ReadTimeout)
+	}
+	if limits.MaxHeaderBytes != http.DefaultMaxHeaderBytes {
+		t.Errorf("max header bytes was %d", limits.MaxHeaderBytes)
+	}
+}
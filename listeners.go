@@ -0,0 +1,274 @@
+package celerity
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// listenFDsStart is the first file descriptor passed by systemd socket
+// activation.
+const listenFDsStart = 3
+
+// Listen creates listeners for an address. The following forms are supported:
+//
+//	host:port                        a TCP address
+//	tcp://host:port                  a TCP address
+//	unix:///run/app.sock?mode=0660   a Unix domain socket with optional permissions
+//	fd://3                           an inherited file descriptor
+//	fd://                            all descriptors passed by socket activation
+//
+// Socket activation follows the systemd protocol using the LISTEN_FDS and
+// LISTEN_PID environment variables.
+func Listen(addr string) ([]net.Listener, error) {
+	if !strings.Contains(addr, "://") {
+		addr = "tcp://" + addr
+	}
+	u, err := url.Parse(addr)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "tcp":
+		l, err := net.Listen("tcp", u.Host)
+		if err != nil {
+			return nil, err
+		}
+		return []net.Listener{l}, nil
+	case "unix":
+		l, err := listenUnix(u.Path, u.Query().Get("mode"))
+		if err != nil {
+			return nil, err
+		}
+		return []net.Listener{l}, nil
+	case "fd":
+		if u.Host == "" {
+			return activationListeners()
+		}
+		fd, err := strconv.Atoi(u.Host)
+		if err != nil {
+			return nil, fmt.Errorf("invalid file descriptor: %s", u.Host)
+		}
+		l, err := fileListener(fd)
+		if err != nil {
+			return nil, err
+		}
+		return []net.Listener{l}, nil
+	}
+	return nil, fmt.Errorf("unsupported listener address: %s", addr)
+}
+
+// listenAll creates listeners for each address. If any address fails the
+// listeners that were already created are closed.
+func listenAll(addrs []string) ([]net.Listener, error) {
+	listeners := []net.Listener{}
+	for _, addr := range addrs {
+		ls, err := Listen(addr)
+		if err != nil {
+			closeListeners(listeners)
+			return nil, err
+		}
+		listeners = append(listeners, ls...)
+	}
+	return listeners, nil
+}
+
+// listenUnix listens on a Unix domain socket. A stale socket file left behind
+// by a previous process is removed first. A socket that still accepts
+// connections is left alone and an error is returned.
+func listenUnix(path, mode string) (net.Listener, error) {
+	if stat, err := os.Stat(path); err == nil && stat.Mode()&os.ModeSocket != 0 {
+		conn, err := net.Dial("unix", path)
+		if err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("listen unix %s: address already in use", path)
+		}
+		if !errors.Is(err, syscall.ECONNREFUSED) && !errors.Is(err, syscall.ENOENT) {
+			return nil, err
+		}
+		os.Remove(path)
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if mode != "" {
+		perm, err := strconv.ParseUint(mode, 8, 32)
+		if err != nil {
+			l.Close()
+			return nil, fmt.Errorf("invalid socket mode: %s", mode)
+		}
+		if err := os.Chmod(path, os.FileMode(perm)); err != nil {
+			l.Close()
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+// fileListener creates a listener from an inherited file descriptor.
+func fileListener(fd int) (net.Listener, error) {
+	f := os.NewFile(uintptr(fd), "fd"+strconv.Itoa(fd))
+	if f == nil {
+		return nil, fmt.Errorf("invalid file descriptor: %d", fd)
+	}
+	defer f.Close()
+	return net.FileListener(f)
+}
+
+// activationListeners creates listeners for the file descriptors passed by
+// systemd socket activation.
+func activationListeners() ([]net.Listener, error) {
+	if pid := os.Getenv("LISTEN_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return nil, errors.New("socket activation descriptors belong to another process")
+	}
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || count < 1 {
+		return nil, errors.New("no file descriptors passed by socket activation")
+	}
+	listeners := []net.Listener{}
+	for fd := listenFDsStart; fd < listenFDsStart+count; fd++ {
+		l, err := fileListener(fd)
+		if err != nil {
+			closeListeners(listeners)
+			return nil, err
+		}
+		listeners = append(listeners, l)
+	}
+	return listeners, nil
+}
+
+func closeListeners(listeners []net.Listener) {
+	for _, l := range listeners {
+		l.Close()
+	}
+}
+
+// Serve serves connections on the given listeners until the server fails or is
+// stopped with Shutdown, in which case it returns nil. The start hooks run
+// before connections are accepted and the ready hooks once serving begins.
+func (s *Server) Serve(listeners ...net.Listener) error {
+	if err := s.runStartHooks(); err != nil {
+		closeListeners(listeners)
+		return err
+	}
+	hs, err := s.newHTTPServer(s, nil)
+	if err != nil {
+		closeListeners(listeners)
+		return err
+	}
+	return s.serveListeners(hs, listeners, false, true)
+}
+
+// serveListeners serves on every listener with the same http.Server. If one of
+// them fails the others are closed. When ready is set the ready hooks run once
+// all listeners are being served and an error from them stops the server.
+func (s *Server) serveListeners(hs *http.Server, listeners []net.Listener, useTLS, ready bool) error {
+	if len(listeners) == 0 {
+		return errors.New("no listeners to serve")
+	}
+	listeners = s.Limits.limitListeners(listeners)
+	errs := make(chan error, len(listeners))
+	for _, l := range listeners {
+		go func(l net.Listener) {
+			if useTLS {
+				errs <- hs.ServeTLS(l, "", "")
+			} else {
+				errs <- hs.Serve(l)
+			}
+		}(l)
+	}
+	var firstErr error
+	if ready {
+		if err := s.runReadyHooks(); err != nil {
+			firstErr = err
+			hs.Close()
+		}
+	}
+	for range listeners {
+		if err := <-errs; err != http.ErrServerClosed && firstErr == nil {
+			firstErr = err
+			hs.Close()
+		}
+	}
+	return firstErr
+}
+
+// AdminScope returns the scope served by the admin listener. Routes added to
+// it are only reachable through StartAdmin and never on the main listeners.
+//
+//	admin := server.AdminScope()
+//	admin.GET("/debug/stats", statsHandler)
+//	go server.StartAdmin("127.0.0.1:9000")
+func (s *Server) AdminScope() *Scope {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if s.adminRouter == nil {
+		s.adminRouter = NewRouter()
+		s.adminRouter.Root.server = s
+	}
+	return s.adminRouter.Root
+}
+
+// StartAdmin serves the admin scope on an address accepted by Listen. It blocks
+// until the server fails or is stopped with Shutdown.
+func (s *Server) StartAdmin(addr string) error {
+	listeners, err := Listen(addr)
+	if err != nil {
+		return err
+	}
+	hs, err := s.adminServer()
+	if err != nil {
+		closeListeners(listeners)
+		return err
+	}
+	return s.serveListeners(hs, listeners, false, false)
+}
+
+// adminServer creates the http.Server for the admin scope.
+func (s *Server) adminServer() (*http.Server, error) {
+	s.AdminScope()
+	router := s.adminRouter
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.handle(router, w, r)
+	})
+	return s.newHTTPServer(handler, nil)
+}
+
+// serveWithAdmin binds the admin address and serves the admin scope alongside
+// the main listeners started by serve. The start hooks run before the admin
+// address is bound, and an admin address that cannot be bound fails the start
+// before serve is called. The first error from either server is returned once
+// both have stopped.
+func (s *Server) serveWithAdmin(addr string, serve func() error) error {
+	if err := s.runStartHooks(); err != nil {
+		return err
+	}
+	listeners, err := Listen(addr)
+	if err != nil {
+		return err
+	}
+	hs, err := s.adminServer()
+	if err != nil {
+		closeListeners(listeners)
+		return err
+	}
+	adminErr := make(chan error, 1)
+	go func() {
+		adminErr <- s.serveListeners(hs, listeners, false, false)
+	}()
+	err = serve()
+	if err != nil {
+		hs.Close()
+	}
+	if aerr := <-adminErr; err == nil {
+		err = aerr
+	}
+	return err
+}
@@ -0,0 +1,241 @@
+package celerity
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestListeners(t *testing.T) {
+	dir, err := ioutil.TempDir("", "celerity-listen")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer os.RemoveAll(dir)
+	sock := filepath.Join(dir, "app.sock")
+
+	// An inherited descriptor is simulated with a duplicate of a TCP listener.
+	inherited, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	f, err := inherited.(*net.TCPListener).File()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	fd, err := syscall.Dup(int(f.Fd()))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	f.Close()
+	inherited.Close()
+	fdAddr := inherited.Addr().String()
+
+	tcpAddr := freeAddr(t)
+	listeners, err := listenAll([]string{
+		tcpAddr,
+		"unix://" + sock + "?mode=0600",
+		"fd://" + strconv.Itoa(fd),
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	server := New()
+	server.GET("/public", func(c Context) Response {
+		return c.Raw([]byte("public"))
+	})
+	server.AdminScope().GET("/stats", func(c Context) Response {
+		return c.Raw([]byte("stats"))
+	})
+	adminAddr := freeAddr(t)
+	go server.Serve(listeners...)
+	go server.StartAdmin(adminAddr)
+	waitForServer(t, tcpAddr)
+	waitForServer(t, adminAddr)
+
+	unixClient := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return net.Dial("unix", sock)
+		},
+	}}
+	get := func(client *http.Client, url string) (int, string) {
+		res, err := client.Get(url)
+		if err != nil {
+			t.Fatalf("Error requesting url: %s", err.Error())
+		}
+		defer res.Body.Close()
+		b, _ := ioutil.ReadAll(res.Body)
+		return res.StatusCode, string(b)
+	}
+
+	t.Run("tcp", func(t *testing.T) {
+		if _, body := get(http.DefaultClient, "http://"+tcpAddr+"/public"); body != "public" {
+			t.Errorf("body was: %s", body)
+		}
+	})
+
+	t.Run("unix", func(t *testing.T) {
+		if _, body := get(unixClient, "http://unix/public"); body != "public" {
+			t.Errorf("body was: %s", body)
+		}
+		stat, err := os.Stat(sock)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if perm := stat.Mode().Perm(); perm != 0600 {
+			t.Errorf("socket mode was %o", perm)
+		}
+	})
+
+	t.Run("file descriptor", func(t *testing.T) {
+		if _, body := get(http.DefaultClient, "http://"+fdAddr+"/public"); body != "public" {
+			t.Errorf("body was: %s", body)
+		}
+	})
+
+	t.Run("admin", func(t *testing.T) {
+		if _, body := get(http.DefaultClient, "http://"+adminAddr+"/stats"); body != "stats" {
+			t.Errorf("body was: %s", body)
+		}
+		if code, _ := get(http.DefaultClient, "http://"+adminAddr+"/public"); code != 404 {
+			t.Errorf("public route on admin listener returned %d", code)
+		}
+		if code, _ := get(http.DefaultClient, "http://"+tcpAddr+"/stats"); code != 404 {
+			t.Errorf("admin route on public listener returned %d", code)
+		}
+	})
+
+	t.Run("shutdown", func(t *testing.T) {
+		if err := server.Shutdown(context.Background()); err != nil {
+			t.Fatal(err.Error())
+		}
+		for _, addr := range []string{tcpAddr, adminAddr, fdAddr} {
+			if _, err := net.Dial("tcp", addr); err == nil {
+				t.Errorf("%s still accepting connections", addr)
+			}
+		}
+		if _, err := os.Stat(sock); !os.IsNotExist(err) {
+			t.Errorf("socket file was not removed: %v", err)
+		}
+	})
+}
+
+func TestListenUnixInUse(t *testing.T) {
+	dir, err := ioutil.TempDir("", "celerity-listen")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer os.RemoveAll(dir)
+	sock := filepath.Join(dir, "app.sock")
+
+	live, err := Listen("unix://" + sock)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if _, err := Listen("unix://" + sock); err == nil || !strings.Contains(err.Error(), "address already in use") {
+		t.Errorf("socket in use was replaced: %v", err)
+	}
+	if conn, err := net.Dial("unix", sock); err != nil {
+		t.Errorf("live socket was removed: %s", err.Error())
+	} else {
+		conn.Close()
+	}
+
+	live[0].(*net.UnixListener).SetUnlinkOnClose(false)
+	live[0].Close()
+	stale, err := Listen("unix://" + sock)
+	if err != nil {
+		t.Fatalf("stale socket was not replaced: %s", err.Error())
+	}
+	closeListeners(stale)
+}
+
+func TestServeWithAdmin(t *testing.T) {
+	taken, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer taken.Close()
+
+	server := New()
+	served := false
+	err = server.serveWithAdmin(taken.Addr().String(), func() error {
+		served = true
+		return nil
+	})
+	if err == nil {
+		t.Error("admin bind failure was not returned")
+	}
+	if served {
+		t.Error("main listeners were served after the admin bind failed")
+	}
+
+	server = New()
+	adminAddr := freeAddr(t)
+	server.OnStart(func(context.Context) error {
+		return errors.New("not ready")
+	})
+	err = server.serveWithAdmin(adminAddr, func() error {
+		served = true
+		return nil
+	})
+	if err == nil || err.Error() != "not ready" {
+		t.Errorf("start hook error was %v", err)
+	}
+	if served {
+		t.Error("main listeners were served after a start hook failed")
+	}
+	if conn, err := net.Dial("tcp", adminAddr); err == nil {
+		conn.Close()
+		t.Error("admin address was bound after a start hook failed")
+	}
+
+	server = New()
+	server.AdminScope().GET("/stats", func(c Context) Response {
+		return c.Raw([]byte("stats"))
+	})
+	addr := freeAddr(t)
+	server.OnStart(func(context.Context) error {
+		if conn, err := net.Dial("tcp", adminAddr); err == nil {
+			conn.Close()
+			return errors.New("admin address was bound before the start hooks ran")
+		}
+		return nil
+	})
+	errs := make(chan error, 1)
+	go func() {
+		errs <- server.serveWithAdmin(adminAddr, func() error {
+			return server.Start(addr)
+		})
+	}()
+	waitForServer(t, addr)
+	waitForServer(t, adminAddr)
+	server.Shutdown(context.Background())
+	if err := <-errs; err != nil {
+		t.Errorf("shutdown error was %s", err.Error())
+	}
+}
+
+func TestListenErrors(t *testing.T) {
+	os.Unsetenv("LISTEN_FDS")
+	addrs := []string{
+		"udp://127.0.0.1:0",
+		"fd://nope",
+		"fd://",
+		"unix:///nonexistent/dir/app.sock",
+	}
+	for _, addr := range addrs {
+		if _, err := Listen(addr); err == nil {
+			t.Errorf("%s was accepted", addr)
+		}
+	}
+}
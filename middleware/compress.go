@@ -0,0 +1,242 @@
+package middleware
+
+import (
+	"bufio"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/5Sigma/celerity"
+)
+
+// CompressConfig configures the compression middleware and can be passed to
+// CompressWithConfig.
+type CompressConfig struct {
+	// Level is the compression level used for gzip and deflate.
+	Level int
+	// MinSize is the smallest response body, in bytes, that is compressed.
+	MinSize int
+	// ContentTypes is an allow-list of content type prefixes to compress.
+	ContentTypes []string
+}
+
+// NewCompressConfig creates a default configuration for CompressConfig.
+func NewCompressConfig() CompressConfig {
+	return CompressConfig{
+		Level:   gzip.DefaultCompression,
+		MinSize: 1024,
+		ContentTypes: []string{
+			"text/",
+			"application/json",
+			"application/problem+json",
+			"application/javascript",
+			"application/xml",
+			"application/x-yaml",
+			"application/yaml",
+			"image/svg+xml",
+		},
+	}
+}
+
+// Compress creates a new compression middleware with sane defaults.
+//
+// Responses are compressed with gzip or deflate depending on the
+// Accept-Encoding header of the request. This covers adapter output, raw
+// responses and static files. Responses that are already encoded are left
+// untouched.
+func Compress() celerity.MiddlewareHandler {
+	return CompressWithConfig(NewCompressConfig())
+}
+
+// CompressWithConfig creates a new compression middleware with the given
+// config.
+func CompressWithConfig(config CompressConfig) celerity.MiddlewareHandler {
+	return func(next celerity.RouteHandler) celerity.RouteHandler {
+		return func(c celerity.Context) celerity.Response {
+			if c.Writer == nil || c.Header("Upgrade") != "" {
+				return next(c)
+			}
+			c.Writer.Header().Add("Vary", "Accept-Encoding")
+			encoding := acceptedEncoding(c.Header("Accept-Encoding"))
+			if encoding == "" {
+				return next(c)
+			}
+			cw := &compressWriter{
+				ResponseWriter: c.Writer,
+				config:         config,
+				encoding:       encoding,
+				status:         http.StatusOK,
+			}
+			c.Writer = cw
+			r := next(c)
+			r.Writer = cw
+			return r
+		}
+	}
+}
+
+// acceptedEncoding picks gzip or deflate from an Accept-Encoding header,
+// preferring gzip when both are equally acceptable. A wildcard only selects
+// gzip and deflate is only used when it is listed by name. An explicit q=0
+// excludes an encoding even if a wildcard allows it.
+func acceptedEncoding(header string) string {
+	accept := celerity.ParseAccept(header)
+	gzipQ := accept.Quality("gzip")
+	deflateQ := 0.0
+	if entry, idx := accept.Match("deflate"); idx >= 0 && entry.Value == "deflate" {
+		deflateQ = entry.Q
+	}
+	switch {
+	case gzipQ > 0 && gzipQ >= deflateQ:
+		return "gzip"
+	case deflateQ > 0:
+		return "deflate"
+	}
+	return ""
+}
+
+// compressWriter buffers the response until MinSize bytes have been written
+// and then decides whether the response should be compressed.
+type compressWriter struct {
+	http.ResponseWriter
+	config   CompressConfig
+	encoding string
+	status   int
+	buf      []byte
+	decided  bool
+	writer   io.WriteCloser
+}
+
+// WriteHeader records the status code. It is sent once the writer decides if
+// the response will be compressed.
+func (w *compressWriter) WriteHeader(code int) {
+	if w.decided {
+		return
+	}
+	w.status = code
+	if code == http.StatusNotModified || code == http.StatusNoContent {
+		w.decide()
+	}
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if !w.decided {
+		w.buf = append(w.buf, b...)
+		if len(w.buf) < w.config.MinSize {
+			return len(b), nil
+		}
+		if err := w.decide(); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+	if w.writer != nil {
+		return w.writer.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Close flushes any buffered data and finishes the compressed stream.
+func (w *compressWriter) Close() error {
+	if !w.decided {
+		if err := w.decide(); err != nil {
+			return err
+		}
+	}
+	if w.writer != nil {
+		return w.writer.Close()
+	}
+	return nil
+}
+
+// Flush sends any buffered data to the client, compressing it if the response
+// is eligible, and flushes the compressed stream followed by the underlying
+// writer. Responses flushed before MinSize bytes are written are not
+// compressed.
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		if err := w.decide(); err != nil {
+			return
+		}
+	}
+	if f, ok := w.writer.(interface {
+		Flush() error
+	}); ok {
+		if err := f.Flush(); err != nil {
+			return
+		}
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack allows websocket upgrades through the writer.
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if hj, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return hj.Hijack()
+	}
+	return nil, nil, errors.New("the response writer does not support hijacking")
+}
+
+func (w *compressWriter) decide() error {
+	w.decided = true
+	h := w.Header()
+	if w.shouldCompress() {
+		h.Del("Content-Length")
+		h.Set("Content-Encoding", w.encoding)
+		if etag := h.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			h.Set("ETag", "W/"+etag)
+		}
+		var err error
+		if w.encoding == "gzip" {
+			w.writer, err = gzip.NewWriterLevel(w.ResponseWriter, w.config.Level)
+		} else {
+			w.writer, err = zlib.NewWriterLevel(w.ResponseWriter, w.config.Level)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	if len(w.buf) == 0 {
+		return nil
+	}
+	var err error
+	if w.writer != nil {
+		_, err = w.writer.Write(w.buf)
+	} else {
+		_, err = w.ResponseWriter.Write(w.buf)
+	}
+	w.buf = nil
+	return err
+}
+
+func (w *compressWriter) shouldCompress() bool {
+	if w.status < 200 || w.status >= 300 || w.status == http.StatusNoContent ||
+		w.status == http.StatusPartialContent {
+		return false
+	}
+	if len(w.buf) < w.config.MinSize || len(w.buf) == 0 {
+		return false
+	}
+	h := w.Header()
+	if h.Get("Content-Encoding") != "" || h.Get("Content-Range") != "" {
+		return false
+	}
+	ct := h.Get("Content-Type")
+	if ct == "" {
+		ct = http.DetectContentType(w.buf)
+	}
+	ct = strings.ToLower(ct)
+	for _, allowed := range w.config.ContentTypes {
+		if strings.HasPrefix(ct, allowed) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,175 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/5Sigma/celerity"
+	"github.com/spf13/afero"
+)
+
+func TestAcceptedEncoding(t *testing.T) {
+	tests := map[string]string{
+		"":                        "",
+		"gzip":                    "gzip",
+		"deflate, gzip":           "gzip",
+		"gzip;q=0.5, deflate":     "deflate",
+		"br":                      "",
+		"*":                       "gzip",
+		"gzip;q=0, deflate;q=0.1": "deflate",
+		"gzip;q=0":                "",
+		"gzip;q=0, *":             "",
+		"*;q=0.5, deflate":        "deflate",
+		"GZIP;Q=0.5":              "gzip",
+	}
+	for header, expected := range tests {
+		if v := acceptedEncoding(header); v != expected {
+			t.Errorf("encoding for '%s' was '%s'", header, v)
+		}
+	}
+}
+
+func TestCompress(t *testing.T) {
+	large := strings.Repeat("celerity ", 500)
+	adapter := celerity.NewMEMAdapter()
+	celerity.FSAdapter = adapter
+	afero.WriteFile(adapter.MEMFS, "/public/app.css", []byte(large), 0755)
+
+	svr := celerity.New()
+	svr.Use(Compress())
+	svr.GET("/large", func(c celerity.Context) celerity.Response {
+		return c.R(large)
+	})
+	svr.GET("/small", func(c celerity.Context) celerity.Response {
+		return c.R("small")
+	})
+	svr.GET("/raw", func(c celerity.Context) celerity.Response {
+		return c.Raw([]byte(large))
+	})
+	svr.GET("/image", func(c celerity.Context) celerity.Response {
+		c.Response.Header.Set("Content-Type", "image/png")
+		return c.Raw([]byte(large))
+	})
+	svr.GET("/tagged", func(c celerity.Context) celerity.Response {
+		return c.R(large).ETag("v1")
+	})
+	svr.GET("/stream", func(c celerity.Context) celerity.Response {
+		c.Writer.Header().Set("Content-Type", "text/plain")
+		c.Writer.Write([]byte(large))
+		c.Writer.(http.Flusher).Flush()
+		c.Writer.Write([]byte("end"))
+		return c.Raw(nil)
+	})
+	svr.ServePath("/public", "/public")
+
+	ts := httptest.NewServer(svr)
+	defer ts.Close()
+
+	get := func(path, encoding string) (*http.Response, []byte) {
+		req, _ := http.NewRequest(celerity.GET, ts.URL+path, nil)
+		req.Header.Set("Accept-Encoding", encoding)
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Error requesting url: %s", err.Error())
+		}
+		defer res.Body.Close()
+		bbody, _ := ioutil.ReadAll(res.Body)
+		return res, bbody
+	}
+
+	for _, path := range []string{"/large", "/raw", "/public/app.css"} {
+		t.Run("gzip "+path, func(t *testing.T) {
+			res, body := get(path, "gzip")
+			if v := res.Header.Get("Content-Encoding"); v != "gzip" {
+				t.Fatalf("content encoding was %s", v)
+			}
+			if v := res.Header.Get("Vary"); v != "Accept-Encoding" {
+				t.Errorf("vary was %s", v)
+			}
+			if res.ContentLength == int64(len(large)) {
+				t.Errorf("content length was not removed: %d", res.ContentLength)
+			}
+			r, err := gzip.NewReader(strings.NewReader(string(body)))
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			plain, _ := ioutil.ReadAll(r)
+			if !strings.Contains(string(plain), "celerity celerity") {
+				t.Errorf("body was: %s", string(plain))
+			}
+		})
+	}
+
+	t.Run("weak etag", func(t *testing.T) {
+		res, _ := get("/tagged", "gzip")
+		if v := res.Header.Get("ETag"); v != `W/"v1"` {
+			t.Errorf("compressed etag was %s", v)
+		}
+		res, _ = get("/tagged", "identity")
+		if v := res.Header.Get("ETag"); v != `"v1"` {
+			t.Errorf("plain etag was %s", v)
+		}
+	})
+
+	t.Run("flush", func(t *testing.T) {
+		res, body := get("/stream", "gzip")
+		if v := res.Header.Get("Content-Encoding"); v != "gzip" {
+			t.Fatalf("content encoding was %s", v)
+		}
+		r, err := gzip.NewReader(strings.NewReader(string(body)))
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		plain, _ := ioutil.ReadAll(r)
+		if !strings.HasSuffix(string(plain), "celerity end") {
+			t.Errorf("body was: %s", string(plain))
+		}
+	})
+
+	t.Run("deflate", func(t *testing.T) {
+		res, body := get("/large", "deflate")
+		if v := res.Header.Get("Content-Encoding"); v != "deflate" {
+			t.Fatalf("content encoding was %s", v)
+		}
+		r, err := zlib.NewReader(strings.NewReader(string(body)))
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		plain, _ := ioutil.ReadAll(r)
+		if !strings.Contains(string(plain), "celerity celerity") {
+			t.Errorf("body was: %s", string(plain))
+		}
+	})
+
+	t.Run("below minimum size", func(t *testing.T) {
+		res, body := get("/small", "gzip")
+		if v := res.Header.Get("Content-Encoding"); v != "" {
+			t.Errorf("content encoding was %s", v)
+		}
+		if !strings.Contains(string(body), "small") {
+			t.Errorf("body was: %s", string(body))
+		}
+	})
+
+	t.Run("content type not allowed", func(t *testing.T) {
+		res, _ := get("/image", "gzip")
+		if v := res.Header.Get("Content-Encoding"); v != "" {
+			t.Errorf("content encoding was %s", v)
+		}
+	})
+
+	t.Run("not accepted", func(t *testing.T) {
+		res, body := get("/large", "identity")
+		if v := res.Header.Get("Content-Encoding"); v != "" {
+			t.Errorf("content encoding was %s", v)
+		}
+		if !strings.Contains(string(body), "celerity celerity") {
+			t.Errorf("body was not plain")
+		}
+	})
+}
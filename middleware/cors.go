@@ -1,28 +1,66 @@
 package middleware
 
 import (
+	"strconv"
 	"strings"
+	"sync/atomic"
 
 	"github.com/5Sigma/celerity"
+	"github.com/5Sigma/vox"
 )
 
 // CORSConfig configures the cors middleware and can be passed to
-// CORSWithConfig.
+// CORSWithConfig. When loaded with CORSFromConfig the settings are read from
+// keys such as allow-origins and max-age.
 type CORSConfig struct {
-	AllowOrigins     []string
-	AllowMethods     []string
-	AllowHeaders     []string
-	AllowCredentials bool
-	ExposeHeaders    []string
-	Age              int
+	AllowOrigins     []string `mapstructure:"allow-origins"`
+	AllowMethods     []string `mapstructure:"allow-methods"`
+	AllowHeaders     []string `mapstructure:"allow-headers"`
+	AllowCredentials bool     `mapstructure:"allow-credentials"`
+	ExposeHeaders    []string `mapstructure:"expose-headers"`
+	Age              int      `mapstructure:"max-age"`
 }
 
 // CORSWithConfig returns a CORS middleware handler with a specific
 // configuration.
 func CORSWithConfig(config CORSConfig) celerity.MiddlewareHandler {
+	return corsHandler(func() CORSConfig { return config })
+}
+
+// CORSFromConfig returns a CORS middleware configured from a section of the
+// config file. The settings are applied without a restart when the config file
+// is reloaded.
+//
+//	# config.yaml
+//	cors:
+//	  allow-origins: ["https://example.com"]
+//	  max-age: 600
+//
+//	server.Pre(middleware.CORSFromConfig("cors"))
+func CORSFromConfig(key string) celerity.MiddlewareHandler {
+	var current atomic.Value
+	load := func() {
+		config := CORSConfig{}
+		if err := celerity.Config().UnmarshalKey(key, &config); err != nil {
+			vox.Error(err)
+			return
+		}
+		current.Store(config)
+	}
+	current.Store(CORSConfig{})
+	load()
+	celerity.OnConfigChange(key, func(interface{}) {
+		load()
+	})
+	return corsHandler(func() CORSConfig {
+		return current.Load().(CORSConfig)
+	})
+}
+
+func corsHandler(config func() CORSConfig) celerity.MiddlewareHandler {
 	return func(next celerity.RouteHandler) celerity.RouteHandler {
 		return func(c celerity.Context) celerity.Response {
-
+			config := config()
 			origins := strings.Join(config.AllowOrigins, ",")
 			methods := strings.Join(config.AllowMethods, ",")
 			headers := strings.Join(config.AllowHeaders, ",")
@@ -35,7 +73,7 @@ func CORSWithConfig(config CORSConfig) celerity.MiddlewareHandler {
 				c.Response.Header.Set("Access-Control-Allow-Headers", headers)
 			}
 			if config.Age > 0 {
-				c.Response.Header.Set("Access-Control-Max-Age", string(config.Age))
+				c.Response.Header.Set("Access-Control-Max-Age", strconv.Itoa(config.Age))
 			}
 			if config.AllowCredentials {
 				c.Response.Header.Set("Access-Control-Allow-Credentials", "true")
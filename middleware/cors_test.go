@@ -5,6 +5,7 @@ import (
 
 	"github.com/5Sigma/celerity"
 	"github.com/5Sigma/celerity/celeritytest"
+	"github.com/spf13/viper"
 )
 
 func TestCORS(t *testing.T) {
@@ -28,3 +29,28 @@ func TestCORS(t *testing.T) {
 		t.Errorf("allow origins should be * by default: %s", origins)
 	}
 }
+
+func TestCORSFromConfig(t *testing.T) {
+	defer viper.Reset()
+	viper.Set("cors", map[string]interface{}{
+		"allow-origins": []string{"https://example.com"},
+		"max-age":       600,
+	})
+	svr := celerity.New()
+	svr.GET("/foo", func(c celerity.Context) celerity.Response {
+		return c.R(nil)
+	})
+	svr.Pre(CORSFromConfig("cors"))
+
+	reqOpts := celeritytest.RequestOptions{
+		Path:   "/foo",
+		Method: celerity.OPTIONS,
+	}
+	resp, _ := celeritytest.Request(svr, reqOpts)
+	if v := resp.Header.Get("Access-Control-Allow-Origin"); v != "https://example.com" {
+		t.Errorf("allow origins was %s", v)
+	}
+	if v := resp.Header.Get("Access-Control-Max-Age"); v != "600" {
+		t.Errorf("max age was %s", v)
+	}
+}
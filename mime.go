@@ -0,0 +1,80 @@
+package celerity
+
+import (
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+// MIMETypes maps file extensions, including the leading dot, to the content
+// type used when serving files with that extension.
+var MIMETypes = map[string]string{
+	".html":        "text/html; charset=utf-8",
+	".htm":         "text/html; charset=utf-8",
+	".css":         "text/css; charset=utf-8",
+	".js":          "text/javascript; charset=utf-8",
+	".mjs":         "text/javascript; charset=utf-8",
+	".json":        "application/json",
+	".map":         "application/json",
+	".webmanifest": "application/manifest+json",
+	".xml":         "application/xml",
+	".txt":         "text/plain; charset=utf-8",
+	".md":          "text/markdown; charset=utf-8",
+	".csv":         "text/csv; charset=utf-8",
+	".yaml":        "application/x-yaml",
+	".yml":         "application/x-yaml",
+	".pdf":         "application/pdf",
+	".zip":         "application/zip",
+	".wasm":        "application/wasm",
+	".svg":         "image/svg+xml",
+	".png":         "image/png",
+	".jpg":         "image/jpeg",
+	".jpeg":        "image/jpeg",
+	".gif":         "image/gif",
+	".webp":        "image/webp",
+	".avif":        "image/avif",
+	".ico":         "image/x-icon",
+	".woff":        "font/woff",
+	".woff2":       "font/woff2",
+	".ttf":         "font/ttf",
+	".otf":         "font/otf",
+	".eot":         "application/vnd.ms-fontobject",
+	".mp3":         "audio/mpeg",
+	".ogg":         "audio/ogg",
+	".wav":         "audio/wav",
+	".mp4":         "video/mp4",
+	".webm":        "video/webm",
+}
+
+// SetMIMEType sets the content type served for files with the given extension.
+// It takes precedence over the built in MIMETypes table.
+func (s *Server) SetMIMEType(ext, contentType string) {
+	if s.mimeTypes == nil {
+		s.mimeTypes = map[string]string{}
+	}
+	s.mimeTypes[normalizeExt(ext)] = contentType
+}
+
+// contentType returns the content type for a file name. Server overrides are
+// checked first, then the MIMETypes table. If the extension is unknown the
+// content type is sniffed from the start of the file.
+func (s *Server) contentType(name string, head []byte) string {
+	ext := normalizeExt(filepath.Ext(name))
+	if s != nil {
+		if ct, ok := s.mimeTypes[ext]; ok {
+			return ct
+		}
+	}
+	if ct, ok := MIMETypes[ext]; ok {
+		return ct
+	}
+	return http.DetectContentType(head)
+}
+
+func normalizeExt(ext string) string {
+	ext = strings.ToLower(ext)
+	if ext != "" && ext[0] != '.' {
+		ext = "." + ext
+	}
+	return ext
+}
@@ -0,0 +1,46 @@
+package celerity
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+func TestStaticMIMETypes(t *testing.T) {
+	server := New()
+	adapter := NewMEMAdapter()
+	FSAdapter = adapter
+	files := map[string]string{
+		"app.js":       "text/javascript; charset=utf-8",
+		"logo.SVG":     "image/svg+xml",
+		"main.wasm":    "application/wasm",
+		"data.json":    "application/json",
+		"font.woff2":   "font/woff2",
+		"page.unknown": "text/html; charset=utf-8",
+		"app.webapp":   "application/x-web-app-manifest+json",
+	}
+	for name := range files {
+		afero.WriteFile(adapter.MEMFS, "/public/"+name, []byte("<html></html>"), 0755)
+	}
+	server.SetMIMEType("webapp", "application/x-web-app-manifest+json")
+	server.ServePath("/public", "/public")
+
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	for name, expected := range files {
+		res, err := http.Get(ts.URL + "/public/" + name)
+		if err != nil {
+			t.Fatalf("Error requesting url: %s", err.Error())
+		}
+		res.Body.Close()
+		if v := res.Header.Get("Content-Type"); v != expected {
+			t.Errorf("content type for %s was %s", name, v)
+		}
+		if v := res.Header.Get("X-Content-Type-Options"); v != "nosniff" {
+			t.Errorf("content type options for %s was %s", name, v)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package celerity
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	// ErrNotAcceptable is returned by a response adapter when none of the media
+	// types accepted by the client can be produced. The server responds with a
+	// 406 Not Acceptable status.
+	ErrNotAcceptable = errors.New("none of the accepted media types can be produced")
+
+	// ErrUnsupportedData is returned by a ResponseEncoder when the response data
+	// cannot be represented in its format. The negotiating adapter will try the
+	// next acceptable encoder.
+	ErrUnsupportedData = errors.New("the response data is not supported by the encoder")
+)
+
+// ResponseEncoder encodes a response into a specific media type. Encoders are
+// registered with the NegotiatingResponseAdapter.
+type ResponseEncoder func(Context, Response) ([]byte, error)
+
+type negotiatedEncoder struct {
+	ContentType string
+	Encoder     ResponseEncoder
+}
+
+// NegotiatingResponseAdapter selects an encoder for the response based on the
+// Accept header of the request. The first registered encoder is used when the
+// client does not send an Accept header or accepts any media type.
+//
+//	svr := celerity.New()
+//	svr.ResponseAdapter = celerity.NewNegotiatingResponseAdapter()
+type NegotiatingResponseAdapter struct {
+	encoders []negotiatedEncoder
+}
+
+// NewNegotiatingResponseAdapter creates a new negotiating adapter with
+// encoders registered for JSON, XML, MessagePack, YAML and CSV.
+func NewNegotiatingResponseAdapter() *NegotiatingResponseAdapter {
+	ra := &NegotiatingResponseAdapter{}
+	ra.Register("application/json", JSONEncoder)
+	ra.Register("application/xml", XMLEncoder)
+	ra.Register("text/xml", XMLEncoder)
+	ra.Register("application/msgpack", MsgPackEncoder)
+	ra.Register("application/x-msgpack", MsgPackEncoder)
+	ra.Register("application/yaml", YAMLEncoder)
+	ra.Register("application/x-yaml", YAMLEncoder)
+	ra.Register("text/yaml", YAMLEncoder)
+	ra.Register("text/csv", CSVEncoder)
+	return ra
+}
+
+// Register adds an encoder for a media type. Registering a media type a second
+// time replaces its encoder.
+func (ra *NegotiatingResponseAdapter) Register(contentType string, enc ResponseEncoder) {
+	contentType = strings.ToLower(contentType)
+	for i := range ra.encoders {
+		if ra.encoders[i].ContentType == contentType {
+			ra.encoders[i].Encoder = enc
+			return
+		}
+	}
+	ra.encoders = append(ra.encoders, negotiatedEncoder{
+		ContentType: contentType,
+		Encoder:     enc,
+	})
+}
+
+// Process - Process the response using the best encoder for the request.
+func (ra *NegotiatingResponseAdapter) Process(c Context, r Response) ([]byte, error) {
+	if c.Writer != nil {
+		c.Writer.Header().Add("Vary", "Accept")
+	}
+	accept := ""
+	if c.Request != nil {
+		accept = c.Header("Accept")
+	}
+
+	candidates := ra.negotiate(accept)
+	if len(candidates) == 0 {
+		return nil, ErrNotAcceptable
+	}
+	for _, enc := range candidates {
+		buf, err := enc.Encoder(c, r)
+		if err == ErrUnsupportedData {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		ra.setContentType(c, enc.ContentType)
+		return buf, nil
+	}
+
+	// Errors are rendered with the default encoder when the accepted formats
+	// cannot represent them.
+	if r.Error != nil && len(ra.encoders) > 0 {
+		enc := ra.encoders[0]
+		buf, err := enc.Encoder(c, r)
+		if err == nil {
+			ra.setContentType(c, enc.ContentType)
+		}
+		return buf, err
+	}
+	return nil, ErrNotAcceptable
+}
+
+func (ra *NegotiatingResponseAdapter) setContentType(c Context, contentType string) {
+	if c.Writer == nil {
+		return
+	}
+	if strings.HasPrefix(contentType, "text/") {
+		contentType += "; charset=utf-8"
+	}
+	c.Writer.Header().Set("Content-Type", contentType)
+}
+
+// negotiate returns the registered encoders that are acceptable for the given
+// Accept header in order of preference. Media types with a quality of zero are
+// excluded even when a wildcard accepts them.
+func (ra *NegotiatingResponseAdapter) negotiate(accept string) []negotiatedEncoder {
+	if strings.TrimSpace(accept) == "" {
+		return ra.encoders
+	}
+	types := make([]string, len(ra.encoders))
+	byType := map[string]negotiatedEncoder{}
+	for i, enc := range ra.encoders {
+		types[i] = enc.ContentType
+		byType[enc.ContentType] = enc
+	}
+	encoders := []negotiatedEncoder{}
+	for _, t := range ParseAccept(accept).Preferred(types) {
+		encoders = append(encoders, byType[t])
+	}
+	return encoders
+}
@@ -0,0 +1,184 @@
+package celerity
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/vmihailenco/msgpack"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestParseAccept(t *testing.T) {
+	accept := ParseAccept("text/html;q=0.5, application/xml, */*;q=0.1, image/png;q=0")
+	if len(accept) != 4 {
+		t.Fatalf("expected 4 entries got %d", len(accept))
+	}
+	preferred := accept.Preferred([]string{"image/png", "text/csv", "text/html", "application/xml"})
+	if strings.Join(preferred, ",") != "application/xml,text/html,text/csv" {
+		t.Errorf("preferred values were %v", preferred)
+	}
+	tests := map[string]float64{
+		"image/png":  0,
+		"image/gif":  0.1,
+		"text/html":  0.5,
+		"text/plain": 0.1,
+	}
+	for value, q := range tests {
+		if v := accept.Quality(value); v != q {
+			t.Errorf("quality of %s was %v", value, v)
+		}
+	}
+	encodings := ParseAccept("gzip;q=0, *")
+	if encodings.Accepts("gzip") || !encodings.Accepts("br") {
+		t.Error("explicit q=0 did not take precedence over the wildcard")
+	}
+}
+
+func TestNegotiatingResponseAdapter(t *testing.T) {
+	type user struct {
+		Name string `json:"name"`
+		Age  int    `json:"age"`
+	}
+	server := New()
+	server.ResponseAdapter = NewNegotiatingResponseAdapter()
+	server.GET("/users", func(c Context) Response {
+		return c.R([]user{{"alice", 30}, {"bob", 25}})
+	})
+	server.GET("/user", func(c Context) Response {
+		return c.R(map[string]interface{}{"name": "alice"})
+	})
+	server.GET("/keys", func(c Context) Response {
+		return c.R(map[string]interface{}{"1 <bad>": "x", "ns:tag": "y"})
+	})
+
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	get := func(path, accept string) (*http.Response, []byte) {
+		req, _ := http.NewRequest(GET, ts.URL+path, nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Error requesting url: %s", err.Error())
+		}
+		defer res.Body.Close()
+		bbody, _ := ioutil.ReadAll(res.Body)
+		return res, bbody
+	}
+
+	t.Run("default", func(t *testing.T) {
+		res, body := get("/user", "")
+		if v := res.Header.Get("Content-Type"); v != "application/json" {
+			t.Errorf("content type was %s", v)
+		}
+		if !strings.Contains(string(body), `"name":"alice"`) {
+			t.Errorf("body was: %s", string(body))
+		}
+	})
+
+	t.Run("xml", func(t *testing.T) {
+		res, body := get("/user", "application/xml")
+		if v := res.Header.Get("Content-Type"); v != "application/xml" {
+			t.Errorf("content type was %s", v)
+		}
+		data := struct {
+			XMLName xml.Name `xml:"response"`
+			Success bool     `xml:"success"`
+			Data    struct {
+				Name string `xml:"name"`
+			} `xml:"data"`
+		}{}
+		if err := xml.Unmarshal(body, &data); err != nil {
+			t.Fatalf("%s: %s", err.Error(), string(body))
+		}
+		if !data.Success || data.Data.Name != "alice" {
+			t.Errorf("body was: %s", string(body))
+		}
+	})
+
+	t.Run("yaml", func(t *testing.T) {
+		res, body := get("/user", "application/x-yaml")
+		if v := res.Header.Get("Content-Type"); v != "application/x-yaml" {
+			t.Errorf("content type was %s", v)
+		}
+		data := map[string]interface{}{}
+		if err := yaml.Unmarshal(body, &data); err != nil {
+			t.Fatal(err.Error())
+		}
+		if data["success"] != true {
+			t.Errorf("body was: %s", string(body))
+		}
+	})
+
+	t.Run("msgpack", func(t *testing.T) {
+		res, body := get("/user", "application/msgpack")
+		if v := res.Header.Get("Content-Type"); v != "application/msgpack" {
+			t.Errorf("content type was %s", v)
+		}
+		data := map[string]interface{}{}
+		if err := msgpack.Unmarshal(body, &data); err != nil {
+			t.Fatal(err.Error())
+		}
+		if data["success"] != true {
+			t.Errorf("body was: %v", data)
+		}
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		res, body := get("/users", "text/csv, application/json;q=0.5")
+		if v := res.Header.Get("Content-Type"); v != "text/csv; charset=utf-8" {
+			t.Errorf("content type was %s", v)
+		}
+		if string(body) != "name,age\nalice,30\nbob,25\n" {
+			t.Errorf("body was: %s", string(body))
+		}
+	})
+
+	t.Run("csv fallback", func(t *testing.T) {
+		res, _ := get("/user", "text/csv, application/json;q=0.5")
+		if v := res.Header.Get("Content-Type"); v != "application/json" {
+			t.Errorf("content type was %s", v)
+		}
+	})
+
+	t.Run("excluded type", func(t *testing.T) {
+		res, _ := get("/user", "*/*;q=0.5, application/xml;q=0")
+		if v := res.Header.Get("Content-Type"); v != "application/json" {
+			t.Errorf("content type was %s", v)
+		}
+		res, _ = get("/user", "*/*;q=0.5, application/json;q=0")
+		if v := res.Header.Get("Content-Type"); v != "application/xml" {
+			t.Errorf("content type with json excluded was %s", v)
+		}
+	})
+
+	t.Run("xml invalid keys", func(t *testing.T) {
+		_, body := get("/keys", "application/xml")
+		if !strings.Contains(string(body), `<entry key="1 &lt;bad&gt;">x</entry>`) {
+			t.Errorf("body was: %s", string(body))
+		}
+		if !strings.Contains(string(body), `<entry key="ns:tag">y</entry>`) {
+			t.Errorf("body was: %s", string(body))
+		}
+		if err := xml.Unmarshal(body, &struct{}{}); err != nil {
+			t.Errorf("invalid xml: %s", err.Error())
+		}
+	})
+
+	t.Run("not acceptable", func(t *testing.T) {
+		res, _ := get("/user", "image/png")
+		if res.StatusCode != http.StatusNotAcceptable {
+			t.Errorf("status code was %d", res.StatusCode)
+		}
+		res, _ = get("/user", "text/csv")
+		if res.StatusCode != http.StatusNotAcceptable {
+			t.Errorf("status code for non slice csv was %d", res.StatusCode)
+		}
+	})
+}
@@ -0,0 +1,181 @@
+package celerity
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// PageConfig configures how pagination parameters are read from the query
+// string of a request. TrustProxy uses the X-Forwarded-Proto header for the
+// scheme of Link URLs and should only be enabled behind a proxy that sets it.
+type PageConfig struct {
+	PageParam      string
+	PerPageParam   string
+	OffsetParam    string
+	CursorParam    string
+	DefaultPerPage int
+	MaxPerPage     int
+	TrustProxy     bool
+}
+
+// DefaultPageConfig is the configuration used by Context.Page.
+var DefaultPageConfig = PageConfig{
+	PageParam:      "page",
+	PerPageParam:   "per_page",
+	OffsetParam:    "offset",
+	CursorParam:    "cursor",
+	DefaultPerPage: 25,
+	MaxPerPage:     100,
+}
+
+// Page is the page of results requested by the client. Page numbers start at
+// 1. When the client requests an offset the page is calculated from it.
+type Page struct {
+	Page       int
+	PerPage    int
+	Offset     int
+	Cursor     string
+	config     PageConfig
+	fromOffset bool
+}
+
+// Page parses the pagination parameters of the request using the
+// DefaultPageConfig.
+//
+//	func listUsers(c celerity.Context) celerity.Response {
+//		p := c.Page()
+//		users, total := db.Users(p.Offset, p.PerPage)
+//		return c.Paginate(users, p, total)
+//	}
+func (c *Context) Page() Page {
+	return c.PageWithConfig(DefaultPageConfig)
+}
+
+// PageWithConfig parses the pagination parameters of the request. The page
+// size is limited to the MaxPerPage of the configuration. Invalid values are
+// ignored, so an offset that is not a number falls back to the page parameter.
+func (c *Context) PageWithConfig(config PageConfig) Page {
+	p := Page{
+		Page:    1,
+		PerPage: config.DefaultPerPage,
+		Cursor:  c.QueryParams.String(config.CursorParam),
+		config:  config,
+	}
+	if v := c.QueryParams.Int(config.PerPageParam); v > 0 {
+		p.PerPage = v
+	}
+	if config.MaxPerPage > 0 && p.PerPage > config.MaxPerPage {
+		p.PerPage = config.MaxPerPage
+	}
+	if p.PerPage <= 0 {
+		p.PerPage = 1
+	}
+
+	if v, err := strconv.Atoi(c.QueryParams.String(config.OffsetParam)); err == nil && v >= 0 {
+		p.Offset = v
+		p.Page = v/p.PerPage + 1
+		p.fromOffset = true
+		return p
+	}
+	if v := c.QueryParams.Int(config.PageParam); v > 0 {
+		p.Page = v
+	}
+	p.Offset = (p.Page - 1) * p.PerPage
+	return p
+}
+
+// TotalPages returns the number of pages needed for the total number of
+// results.
+func (p Page) TotalPages(total int) int {
+	if total <= 0 {
+		return 1
+	}
+	return (total + p.PerPage - 1) / p.PerPage
+}
+
+// Paginate responds with a page of data out of a total number of results.
+// The page, page size and totals are added to the response metadata and a Link
+// header is set with the first, last, previous and next pages.
+func (c *Context) Paginate(data interface{}, p Page, total int) Response {
+	pages := p.TotalPages(total)
+	c.Response.Meta["total"] = total
+	c.Response.Meta["page"] = p.Page
+	c.Response.Meta["perPage"] = p.PerPage
+	c.Response.Meta["totalPages"] = pages
+	c.Response.Header.Set("X-Total-Count", strconv.Itoa(total))
+
+	links := []string{}
+	if p.fromOffset {
+		links = append(links, c.pageLink(p.config, "first", p.config.OffsetParam, "0"))
+		if p.Offset > 0 {
+			prev := p.Offset - p.PerPage
+			if prev < 0 {
+				prev = 0
+			}
+			links = append(links, c.pageLink(p.config, "prev", p.config.OffsetParam, strconv.Itoa(prev)))
+		}
+		if p.Offset+p.PerPage < total {
+			links = append(links, c.pageLink(p.config, "next", p.config.OffsetParam, strconv.Itoa(p.Offset+p.PerPage)))
+		}
+		links = append(links, c.pageLink(p.config, "last", p.config.OffsetParam, strconv.Itoa((pages-1)*p.PerPage)))
+	} else {
+		links = append(links, c.pageLink(p.config, "first", p.config.PageParam, "1"))
+		if p.Page > 1 {
+			links = append(links, c.pageLink(p.config, "prev", p.config.PageParam, strconv.Itoa(p.Page-1)))
+		}
+		if p.Page < pages {
+			links = append(links, c.pageLink(p.config, "next", p.config.PageParam, strconv.Itoa(p.Page+1)))
+		}
+		links = append(links, c.pageLink(p.config, "last", p.config.PageParam, strconv.Itoa(pages)))
+	}
+	c.Response.Header.Set("Link", strings.Join(links, ", "))
+	return c.Respond(data)
+}
+
+// PaginateCursor responds with a page of data for cursor based pagination. The
+// next cursor is added to the response metadata and as the next Link. An empty
+// next cursor indicates the last page.
+func (c *Context) PaginateCursor(data interface{}, p Page, next string) Response {
+	c.Response.Meta["perPage"] = p.PerPage
+	c.Response.Meta["nextCursor"] = next
+
+	links := []string{c.pageLink(p.config, "first", p.config.CursorParam, "")}
+	if next != "" {
+		links = append(links, c.pageLink(p.config, "next", p.config.CursorParam, next))
+	}
+	c.Response.Header.Set("Link", strings.Join(links, ", "))
+	return c.Respond(data)
+}
+
+// pageLink builds a link header entry for the current request URL with a
+// query parameter replaced. An empty value removes the parameter.
+func (c *Context) pageLink(config PageConfig, rel, param, value string) string {
+	u := url.URL{Path: "/"}
+	if c.Request != nil {
+		u = *c.Request.URL
+		u.Host = c.Request.Host
+		u.Scheme = "http"
+		if c.Request.TLS != nil {
+			u.Scheme = "https"
+		}
+		if config.TrustProxy {
+			switch proto := strings.ToLower(c.Header("X-Forwarded-Proto")); proto {
+			case "http", "https":
+				u.Scheme = proto
+			}
+		}
+	}
+	q := u.Query()
+	if value == "" {
+		q.Del(param)
+	} else {
+		q.Set(param, value)
+	}
+	u.RawQuery = q.Encode()
+	if u.Host == "" {
+		u.Scheme = ""
+	}
+	return fmt.Sprintf(`<%s>; rel="%s"`, u.String(), rel)
+}
@@ -0,0 +1,104 @@
+package celerity
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestPage(t *testing.T) {
+	tests := []struct {
+		Query   string
+		Page    int
+		PerPage int
+		Offset  int
+	}{
+		{"", 1, 25, 0},
+		{"page=3&per_page=10", 3, 10, 20},
+		{"page=2&per_page=1000", 2, 100, 100},
+		{"offset=45&per_page=10", 5, 10, 45},
+		{"page=bad", 1, 25, 0},
+		{"offset=bad&page=3&per_page=10", 3, 10, 20},
+		{"offset=-10&per_page=10", 1, 10, 0},
+	}
+	for _, test := range tests {
+		req, _ := http.NewRequest(GET, "/users?"+test.Query, nil)
+		c := RequestContext(req)
+		c.SetQueryParamsFromURL(req.URL)
+		p := c.Page()
+		if p.Page != test.Page || p.PerPage != test.PerPage || p.Offset != test.Offset {
+			t.Errorf("incorrect page for '%s': %d %d %d", test.Query, p.Page, p.PerPage, p.Offset)
+		}
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	t.Run("page", func(t *testing.T) {
+		req, _ := http.NewRequest(GET, "http://example.com/users?page=2&per_page=10&sort=name", nil)
+		c := RequestContext(req)
+		c.SetQueryParamsFromURL(req.URL)
+		r := c.Paginate([]string{}, c.Page(), 35)
+		if r.Meta["totalPages"] != 4 || r.Meta["total"] != 35 || r.Meta["page"] != 2 {
+			t.Errorf("meta was %v", r.Meta)
+		}
+		expected := `<http://example.com/users?page=1&per_page=10&sort=name>; rel="first", ` +
+			`<http://example.com/users?page=1&per_page=10&sort=name>; rel="prev", ` +
+			`<http://example.com/users?page=3&per_page=10&sort=name>; rel="next", ` +
+			`<http://example.com/users?page=4&per_page=10&sort=name>; rel="last"`
+		if v := r.Header.Get("Link"); v != expected {
+			t.Errorf("link header was %s", v)
+		}
+	})
+
+	t.Run("offset", func(t *testing.T) {
+		req, _ := http.NewRequest(GET, "http://example.com/users?offset=0&per_page=10", nil)
+		c := RequestContext(req)
+		c.SetQueryParamsFromURL(req.URL)
+		r := c.Paginate([]string{}, c.Page(), 15)
+		expected := `<http://example.com/users?offset=0&per_page=10>; rel="first", ` +
+			`<http://example.com/users?offset=10&per_page=10>; rel="next", ` +
+			`<http://example.com/users?offset=10&per_page=10>; rel="last"`
+		if v := r.Header.Get("Link"); v != expected {
+			t.Errorf("link header was %s", v)
+		}
+	})
+
+	t.Run("forwarded proto", func(t *testing.T) {
+		req, _ := http.NewRequest(GET, "http://example.com/users", nil)
+		req.Header.Set("X-Forwarded-Proto", "https")
+		c := RequestContext(req)
+		c.SetQueryParamsFromURL(req.URL)
+		r := c.Paginate([]string{}, c.Page(), 5)
+		if v := r.Header.Get("Link"); !strings.HasPrefix(v, "<http://example.com/") {
+			t.Errorf("untrusted header was used: %s", v)
+		}
+
+		config := DefaultPageConfig
+		config.TrustProxy = true
+		c = RequestContext(req)
+		c.SetQueryParamsFromURL(req.URL)
+		r = c.Paginate([]string{}, c.PageWithConfig(config), 5)
+		if v := r.Header.Get("Link"); !strings.HasPrefix(v, "<https://example.com/") {
+			t.Errorf("trusted header was not used: %s", v)
+		}
+	})
+
+	t.Run("cursor", func(t *testing.T) {
+		req, _ := http.NewRequest(GET, "http://example.com/events?cursor=abc", nil)
+		c := RequestContext(req)
+		c.SetQueryParamsFromURL(req.URL)
+		p := c.Page()
+		if p.Cursor != "abc" {
+			t.Errorf("cursor was %s", p.Cursor)
+		}
+		r := c.PaginateCursor([]string{}, p, "def")
+		if r.Meta["nextCursor"] != "def" {
+			t.Errorf("meta was %v", r.Meta)
+		}
+		expected := `<http://example.com/events>; rel="first", ` +
+			`<http://example.com/events?cursor=def>; rel="next"`
+		if v := r.Header.Get("Link"); v != expected {
+			t.Errorf("link header was %s", v)
+		}
+	})
+}
@@ -0,0 +1,120 @@
+package celerity
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// ProblemContentType is the media type used for RFC 7807 problem documents.
+const ProblemContentType = "application/problem+json"
+
+// Problem describes an error using the RFC 7807 problem details format. A
+// Problem can be used as the error of a response to control the document
+// produced by the ProblemResponseAdapter.
+//
+//	return c.Problem(&celerity.Problem{
+//		Type:       "https://example.com/probs/out-of-credit",
+//		Title:      "You do not have enough credit.",
+//		Status:     403,
+//		Detail:     "Your current balance is 30, but that costs 50.",
+//		Extensions: map[string]interface{}{"balance": 30},
+//	})
+type Problem struct {
+	Type       string
+	Title      string
+	Status     int
+	Detail     string
+	Instance   string
+	Extensions map[string]interface{}
+}
+
+// NewProblem creates a new problem for the given status and detail message.
+func NewProblem(status int, detail string) *Problem {
+	return &Problem{
+		Status: status,
+		Detail: detail,
+	}
+}
+
+// Error returns the detail of the problem or its title if no detail is set.
+// It allows a Problem to be used as a response error.
+func (p *Problem) Error() string {
+	if p.Detail != "" {
+		return p.Detail
+	}
+	if p.Title != "" {
+		return p.Title
+	}
+	return http.StatusText(p.Status)
+}
+
+// Problem returns an error response for a problem. The status code of the
+// response is taken from the problem. The problem is copied so it can be
+// shared between requests.
+func (c *Context) Problem(p *Problem) Response {
+	problem := *p
+	if problem.Status == 0 {
+		problem.Status = http.StatusInternalServerError
+	}
+	return c.Error(problem.Status, &problem)
+}
+
+// ProblemResponseAdapter renders error responses as RFC 7807
+// application/problem+json documents. Successful responses are passed to the
+// Success adapter, which defaults to the JSONResponseAdapter.
+//
+// Errors that are not a *Problem are converted using the status code of the
+// response. The request ID is used as the problem instance and the response
+// metadata is added as extension members.
+type ProblemResponseAdapter struct {
+	Success ResponseAdapter
+}
+
+// Process - Process the response into JSON or a problem document.
+func (ra *ProblemResponseAdapter) Process(c Context, r Response) ([]byte, error) {
+	if r.Error == nil {
+		if ra.Success == nil {
+			return (&JSONResponseAdapter{}).Process(c, r)
+		}
+		return ra.Success.Process(c, r)
+	}
+
+	doc := map[string]interface{}{}
+	for k, v := range r.Meta {
+		doc[k] = v
+	}
+
+	var p *Problem
+	if !errors.As(r.Error, &p) {
+		p = &Problem{Detail: r.Error.Error()}
+	}
+	for k, v := range p.Extensions {
+		doc[k] = v
+	}
+
+	doc["type"] = p.Type
+	if p.Type == "" {
+		doc["type"] = "about:blank"
+	}
+	doc["status"] = p.Status
+	if p.Status == 0 {
+		doc["status"] = r.StatusCode
+	}
+	doc["title"] = p.Title
+	if p.Title == "" {
+		doc["title"] = http.StatusText(doc["status"].(int))
+	}
+	if p.Detail != "" {
+		doc["detail"] = p.Detail
+	}
+	doc["instance"] = p.Instance
+	if p.Instance == "" {
+		doc["instance"] = c.RequestID
+	}
+
+	if c.Writer != nil {
+		c.Writer.Header().Set("Content-Type", ProblemContentType)
+	}
+	return json.Marshal(doc)
+}
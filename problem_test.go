@@ -0,0 +1,183 @@
+package celerity
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProblemResponseAdapter(t *testing.T) {
+	server := New()
+	server.ResponseAdapter = &ProblemResponseAdapter{}
+	server.GET("/ok", func(c Context) Response {
+		return c.R("test")
+	})
+	server.GET("/error", func(c Context) Response {
+		return c.Error(409, errors.New("already exists")).MetaValue("field", "name")
+	})
+	server.GET("/problem", func(c Context) Response {
+		return c.Problem(&Problem{
+			Type:       "https://example.com/probs/out-of-credit",
+			Title:      "You do not have enough credit.",
+			Status:     403,
+			Extensions: map[string]interface{}{"balance": 30},
+		})
+	})
+
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	t.Run("success", func(t *testing.T) {
+		res, err := http.Get(ts.URL + "/ok")
+		if err != nil {
+			t.Fatalf("Error requesting url: %s", err.Error())
+		}
+		defer res.Body.Close()
+		bbody, _ := ioutil.ReadAll(res.Body)
+		data := map[string]interface{}{}
+		json.Unmarshal(bbody, &data)
+		if data["data"] != "test" {
+			t.Errorf("body was: %s", string(bbody))
+		}
+		if v := res.Header.Get("Content-Type"); v == ProblemContentType {
+			t.Errorf("content type was %s", v)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		res, err := http.Get(ts.URL + "/error")
+		if err != nil {
+			t.Fatalf("Error requesting url: %s", err.Error())
+		}
+		defer res.Body.Close()
+		if res.StatusCode != 409 {
+			t.Errorf("status code was %d", res.StatusCode)
+		}
+		if v := res.Header.Get("Content-Type"); v != ProblemContentType {
+			t.Errorf("content type was %s", v)
+		}
+		bbody, _ := ioutil.ReadAll(res.Body)
+		data := map[string]interface{}{}
+		json.Unmarshal(bbody, &data)
+		expected := map[string]interface{}{
+			"type":   "about:blank",
+			"title":  "Conflict",
+			"status": float64(409),
+			"detail": "already exists",
+			"field":  "name",
+		}
+		for k, v := range expected {
+			if data[k] != v {
+				t.Errorf("%s was %v: %s", k, data[k], string(bbody))
+			}
+		}
+		if v, _ := data["instance"].(string); len(v) != 32 {
+			t.Errorf("instance should be the request id: %v", data["instance"])
+		}
+	})
+
+	t.Run("problem", func(t *testing.T) {
+		res, err := http.Get(ts.URL + "/problem")
+		if err != nil {
+			t.Fatalf("Error requesting url: %s", err.Error())
+		}
+		defer res.Body.Close()
+		if res.StatusCode != 403 {
+			t.Errorf("status code was %d", res.StatusCode)
+		}
+		bbody, _ := ioutil.ReadAll(res.Body)
+		data := map[string]interface{}{}
+		json.Unmarshal(bbody, &data)
+		if data["type"] != "https://example.com/probs/out-of-credit" {
+			t.Errorf("type was %v", data["type"])
+		}
+		if data["title"] != "You do not have enough credit." {
+			t.Errorf("title was %v", data["title"])
+		}
+		if data["balance"] != float64(30) {
+			t.Errorf("extension member was %v", data["balance"])
+		}
+		if _, ok := data["detail"]; ok {
+			t.Errorf("detail should be omitted: %s", string(bbody))
+		}
+	})
+}
+
+func TestContextProblem(t *testing.T) {
+	shared := &Problem{Title: "Not allowed"}
+	c := NewContext()
+	r := c.Problem(shared)
+	if r.StatusCode != 500 {
+		t.Errorf("status code was %d", r.StatusCode)
+	}
+	if shared.Status != 0 {
+		t.Error("problem passed to Context.Problem was modified")
+	}
+
+	wrapped := fmt.Errorf("charging card: %w", &Problem{Title: "Out of credit", Status: 403})
+	body, err := (&ProblemResponseAdapter{}).Process(c, c.Error(403, wrapped))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	doc := map[string]interface{}{}
+	json.Unmarshal(body, &doc)
+	if doc["title"] != "Out of credit" {
+		t.Errorf("wrapped problem was not used: %s", string(body))
+	}
+}
+
+func TestScopedResponseAdapter(t *testing.T) {
+	server := New()
+	api := server.Scope("/api")
+	api.SetResponseAdapter(&ProblemResponseAdapter{})
+	api.GET("/foo", func(c Context) Response {
+		return c.Error(400, errors.New("bad"))
+	})
+	api.GET("/panic", func(c Context) Response {
+		panic("uh oh")
+	})
+	server.GET("/foo", func(c Context) Response {
+		return c.Error(400, errors.New("bad"))
+	})
+
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	{
+		res, err := http.Get(ts.URL + "/api/foo")
+		if err != nil {
+			t.Fatalf("Error requesting url: %s", err.Error())
+		}
+		res.Body.Close()
+		if v := res.Header.Get("Content-Type"); v != ProblemContentType {
+			t.Errorf("scoped content type was %s", v)
+		}
+	}
+	{
+		res, err := http.Get(ts.URL + "/api/panic")
+		if err != nil {
+			t.Fatalf("Error requesting url: %s", err.Error())
+		}
+		res.Body.Close()
+		if res.StatusCode != 500 {
+			t.Errorf("panic status code was %d", res.StatusCode)
+		}
+		if v := res.Header.Get("Content-Type"); v != ProblemContentType {
+			t.Errorf("panic content type was %s", v)
+		}
+	}
+	{
+		res, err := http.Get(ts.URL + "/foo")
+		if err != nil {
+			t.Fatalf("Error requesting url: %s", err.Error())
+		}
+		res.Body.Close()
+		if v := res.Header.Get("Content-Type"); v == ProblemContentType {
+			t.Errorf("root content type was %s", v)
+		}
+	}
+}
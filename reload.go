@@ -0,0 +1,256 @@
+package celerity
+
+import (
+	"path/filepath"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/5Sigma/vox"
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/cast"
+	"github.com/spf13/viper"
+)
+
+// configSubscription is a callback for changes to a configuration key.
+type configSubscription struct {
+	key   string
+	value interface{}
+	f     func(interface{})
+}
+
+var (
+	configSubscriptions = []*configSubscription{}
+	configMx            sync.Mutex
+	reloadMx            sync.Mutex
+	watchOnce           sync.Once
+	currentConfig       atomic.Value
+)
+
+// Config returns the current configuration. Until the config file is reloaded
+// this is the global viper instance. Each reload builds a new instance and
+// replaces the previous one as a whole, so a value read from it is never from
+// a partially loaded file. Request handlers should read settings that can be
+// reloaded through Config and must not modify it.
+//
+//	server.GET("/", func(c celerity.Context) celerity.Response {
+//		return c.R(celerity.Config().GetString("greeting"))
+//	})
+func Config() *viper.Viper {
+	if v := loadedConfig(); v != nil {
+		return v
+	}
+	return viper.GetViper()
+}
+
+// loadedConfig returns the configuration published by the last reload, or nil
+// if the config file has not been reloaded.
+func loadedConfig() *viper.Viper {
+	v, _ := currentConfig.Load().(*viper.Viper)
+	return v
+}
+
+// OnConfigChange registers a function that is called with the new value when
+// a configuration key changes after the config file is reloaded. Keys can name
+// a section, such as "cors", in which case any change within it triggers the
+// callback. Callbacks run one at a time in the order they were registered.
+//
+//	celerity.OnConfigChange("log-level", func(v interface{}) {
+//		setLevel(cast.ToString(v))
+//	})
+func OnConfigChange(key string, f func(value interface{})) {
+	configMx.Lock()
+	configSubscriptions = append(configSubscriptions, &configSubscription{
+		key:   key,
+		value: Config().Get(key),
+		f:     f,
+	})
+	configMx.Unlock()
+}
+
+// WatchConfig watches the config file and the config file for the current
+// environment and reloads the configuration when either changes. It is enabled
+// for the run command with the --watch-config flag.
+//
+// Reloaded settings are available through Config, LiveValue and
+// OnConfigChange. The global viper instance keeps the settings read at
+// startup.
+func WatchConfig() {
+	watchOnce.Do(func() {
+		base := viper.ConfigFileUsed()
+		if base == "" {
+			return
+		}
+		if _, err := watchConfigFiles(base); err != nil {
+			vox.Error(err)
+		}
+	})
+}
+
+// watchConfigFiles reloads the configuration when the base config file or the
+// config file for the current environment is written, created, renamed or
+// removed. The directory is watched so files replaced by editors are seen. The
+// returned function stops watching.
+func watchConfigFiles(base string) (func(), error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err := watcher.Add(filepath.Dir(base)); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+	base = filepath.Clean(base)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case e, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				name := filepath.Clean(e.Name)
+				envFile := filepath.Clean(environmentConfigFile(base, viper.GetString("env")))
+				if (name != base && name != envFile) || e.Op == fsnotify.Chmod {
+					continue
+				}
+				if err := reloadConfig(); err != nil {
+					vox.Error(err)
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				vox.Error(err)
+			}
+		}
+	}()
+	return func() {
+		watcher.Close()
+		<-done
+	}, nil
+}
+
+// reloadConfig reads the config files into a new configuration, publishes it
+// and notifies subscribers of changed keys. If either file cannot be read the
+// current configuration is kept.
+func reloadConfig() error {
+	reloadMx.Lock()
+	defer reloadMx.Unlock()
+	base := viper.ConfigFileUsed()
+	if base == "" {
+		return nil
+	}
+	v, err := buildConfig(base, viper.GetString("env"))
+	if err != nil {
+		return err
+	}
+	currentConfig.Store(v)
+	changed := []*configSubscription{}
+	configMx.Lock()
+	for _, sub := range configSubscriptions {
+		value := v.Get(sub.key)
+		if reflect.DeepEqual(value, sub.value) {
+			continue
+		}
+		sub.value = value
+		changed = append(changed, sub)
+	}
+	configMx.Unlock()
+	for _, sub := range changed {
+		sub.f(sub.value)
+	}
+	return nil
+}
+
+// buildConfig reads the config file and the config file for the environment
+// into a new viper instance. Flags and environment variables keep precedence
+// over the files, and the values of settings the files do not contain are
+// carried over from the global viper instance as defaults.
+func buildConfig(base, env string) (*viper.Viper, error) {
+	v := viper.New()
+	if err := readConfigFiles(v, base, env); err != nil {
+		return nil, err
+	}
+	flags := map[string]interface{}{}
+	defaults := map[string]interface{}{}
+	for _, key := range viper.AllKeys() {
+		switch configSource(key, configFlags, v) {
+		case "flag":
+			flags[key] = viper.Get(key)
+		case "default":
+			defaults[key] = viper.Get(key)
+		}
+	}
+	for key, value := range defaults {
+		v.SetDefault(key, value)
+	}
+	for key, value := range flags {
+		v.Set(key, value)
+	}
+	v.Set("env", env)
+	v.SetEnvKeyReplacer(envKeyReplacer)
+	v.AutomaticEnv()
+	return v, nil
+}
+
+// LiveValue holds the current value of a configuration key and is updated
+// atomically when the config file is reloaded.
+//
+//	limit := celerity.Live("rate-limit")
+//	server.GET("/", func(c celerity.Context) celerity.Response {
+//		if hits > limit.Int() {
+//			...
+//		}
+//	})
+type LiveValue struct {
+	value atomic.Value
+}
+
+// liveValue wraps values so nil and values of differing types can be stored
+// in an atomic.Value.
+type liveValue struct {
+	v interface{}
+}
+
+// Live returns a LiveValue for a configuration key.
+func Live(key string) *LiveValue {
+	lv := &LiveValue{}
+	lv.value.Store(liveValue{Config().Get(key)})
+	OnConfigChange(key, func(v interface{}) {
+		lv.value.Store(liveValue{v})
+	})
+	return lv
+}
+
+// Value returns the current value.
+func (lv *LiveValue) Value() interface{} {
+	return lv.value.Load().(liveValue).v
+}
+
+// String returns the current value as a string.
+func (lv *LiveValue) String() string {
+	return cast.ToString(lv.Value())
+}
+
+// Int returns the current value as an int.
+func (lv *LiveValue) Int() int {
+	return cast.ToInt(lv.Value())
+}
+
+// Bool returns the current value as a bool.
+func (lv *LiveValue) Bool() bool {
+	return cast.ToBool(lv.Value())
+}
+
+// Duration returns the current value as a time.Duration.
+func (lv *LiveValue) Duration() time.Duration {
+	return cast.ToDuration(lv.Value())
+}
+
+// StringSlice returns the current value as a []string.
+func (lv *LiveValue) StringSlice() []string {
+	return cast.ToStringSlice(lv.Value())
+}
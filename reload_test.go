@@ -0,0 +1,153 @@
+package celerity
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// resetConfig drops the reloaded configuration and config subscriptions.
+func resetConfig() {
+	currentConfig.Store((*viper.Viper)(nil))
+	configSubscriptions = []*configSubscription{}
+	viper.Reset()
+}
+
+func TestConfigReload(t *testing.T) {
+	defer resetConfig()
+	dir, err := ioutil.TempDir("", "celerity-reload")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer os.RemoveAll(dir)
+	base := filepath.Join(dir, "config.yaml")
+	write := func(name, content string) {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+	write("config.yaml", "log-level: info\nrate-limit: 10\ncors:\n  max-age: 60\n")
+	write("config.staging.yaml", "rate-limit: 20\n")
+	viper.Set("env", "staging")
+	if err := readConfigFiles(viper.GetViper(), base, "staging"); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	limit := Live("rate-limit")
+	if limit.Int() != 20 {
+		t.Errorf("initial value was %v", limit.Value())
+	}
+	levels := []string{}
+	OnConfigChange("log-level", func(v interface{}) {
+		levels = append(levels, v.(string))
+	})
+	corsChanges := 0
+	OnConfigChange("cors", func(v interface{}) {
+		corsChanges++
+	})
+
+	write("config.yaml", "log-level: debug\nrate-limit: 10\ncors:\n  max-age: 120\n")
+	write("config.staging.yaml", "rate-limit: 30\n")
+	if err := reloadConfig(); err != nil {
+		t.Fatal(err.Error())
+	}
+	if limit.Int() != 30 {
+		t.Errorf("live value was %v", limit.Value())
+	}
+	if len(levels) != 1 || levels[0] != "debug" {
+		t.Errorf("log level changes were %v", levels)
+	}
+	if corsChanges != 1 {
+		t.Errorf("section changed %d times", corsChanges)
+	}
+	if n := Config().GetInt("cors.max-age"); n != 120 {
+		t.Errorf("reloaded config was %d", n)
+	}
+	if n := viper.GetInt("cors.max-age"); n != 60 {
+		t.Errorf("global config was changed: %d", n)
+	}
+
+	if err := reloadConfig(); err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(levels) != 1 || corsChanges != 1 {
+		t.Error("subscribers notified of unchanged keys")
+	}
+}
+
+func TestWatchConfig(t *testing.T) {
+	defer resetConfig()
+	dir, err := ioutil.TempDir("", "celerity-reload")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer os.RemoveAll(dir)
+	base := filepath.Join(dir, "config.yaml")
+	envFile := filepath.Join(dir, "config.staging.yaml")
+	ioutil.WriteFile(base, []byte("rate-limit: 10\nregion: eu\n"), 0644)
+	ioutil.WriteFile(envFile, []byte("rate-limit: 20\n"), 0644)
+	viper.SetDefault("workers", 2)
+	viper.Set("env", "staging")
+	if err := readConfigFiles(viper.GetViper(), base, "staging"); err != nil {
+		t.Fatal(err.Error())
+	}
+	limit := Live("rate-limit")
+
+	stop, err := watchConfigFiles(base)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer stop()
+	waitFor := func(n int) {
+		for i := 0; i < 100 && limit.Int() != n; i++ {
+			time.Sleep(20 * time.Millisecond)
+		}
+		if limit.Int() != n {
+			t.Fatalf("expected %d got %v", n, limit.Value())
+		}
+	}
+
+	ioutil.WriteFile(envFile, []byte("rate-limit: 30\n"), 0644)
+	waitFor(30)
+	c := NewContext()
+	if c.Env != "staging" {
+		t.Errorf("environment was %s", c.Env)
+	}
+	config := Config()
+	if s := config.GetString("region"); s != "eu" {
+		t.Errorf("base setting was %s", s)
+	}
+	if n := config.GetInt("workers"); n != 2 {
+		t.Errorf("default was %d", n)
+	}
+
+	// Replace the file in one step so the watcher never reads it half written.
+	ioutil.WriteFile(envFile+".tmp", []byte("rate-limit: [\n"), 0644)
+	os.Rename(envFile+".tmp", envFile)
+	time.Sleep(100 * time.Millisecond)
+	if Config().GetInt("rate-limit") != 30 || limit.Int() != 30 {
+		t.Error("invalid config file was published")
+	}
+
+	os.Remove(envFile)
+	waitFor(10)
+}
+
+func TestLiveValue(t *testing.T) {
+	defer resetConfig()
+	viper.Set("timeout", "5s")
+	viper.Set("origins", []string{"a", "b"})
+	if d := Live("timeout").Duration().String(); d != "5s" {
+		t.Errorf("duration was %s", d)
+	}
+	if s := Live("origins").StringSlice(); len(s) != 2 || s[1] != "b" {
+		t.Errorf("slice was %v", s)
+	}
+	if v := Live("missing").Value(); v != nil {
+		t.Errorf("missing value was %v", v)
+	}
+}
@@ -12,7 +12,10 @@ type Response struct {
 	Error      error
 	Meta       map[string]interface{}
 	Header     http.Header
+	Adapter    ResponseAdapter
+	Writer     http.ResponseWriter
 	raw        []byte
+	isRaw      bool
 	Handled    bool
 }
 
@@ -46,12 +49,13 @@ func (r *Response) Success() bool {
 
 // IsRaw determens if the response is a raw response
 func (r *Response) IsRaw() bool {
-	return len(r.raw) > 0
+	return r.isRaw
 }
 
 // SetRaw sets the responses raw output
 func (r *Response) SetRaw(b []byte) {
 	r.raw = b
+	r.isRaw = true
 	r.Data = nil
 }
 
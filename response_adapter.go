@@ -1,6 +1,7 @@
 package celerity
 
 import (
+	"bytes"
 	"encoding/json"
 )
 
@@ -10,31 +11,184 @@ type ResponseAdapter interface {
 	Process(Context, Response) ([]byte, error)
 }
 
-// JSONResponseAdapter - Processes an endpoint response into JSON
-type JSONResponseAdapter struct{}
+// JSONFields are the key names used by the JSONResponseAdapter for the members
+// of the response envelope.
+type JSONFields struct {
+	RequestID string
+	Success   string
+	Error     string
+	Errors    string
+	Data      string
+	Meta      string
+}
+
+var (
+	// DefaultJSONFields are the camel cased envelope keys used by default.
+	DefaultJSONFields = JSONFields{
+		RequestID: "requestId",
+		Success:   "success",
+		Error:     "error",
+		Errors:    "errors",
+		Data:      "data",
+		Meta:      "meta",
+	}
+	// SnakeCaseJSONFields are snake cased envelope keys.
+	SnakeCaseJSONFields = JSONFields{
+		RequestID: "request_id",
+		Success:   "success",
+		Error:     "error",
+		Errors:    "errors",
+		Data:      "data",
+		Meta:      "meta",
+	}
+)
+
+// JSONResponseAdapter - Processes an endpoint response into JSON. The zero
+// value produces the default envelope:
+//
+//	{"requestId": "...", "success": true, "error": "", "data": {}, "meta": {}}
+//
+// The shape of the envelope can be changed with the adapter's options. An
+// adapter can be used for a single scope with Scope.SetResponseAdapter.
+//
+//	svr.ResponseAdapter = &celerity.JSONResponseAdapter{
+//		Fields:      celerity.SnakeCaseJSONFields,
+//		OmitEmpty:   true,
+//		ErrorsArray: true,
+//	}
+type JSONResponseAdapter struct {
+	// Fields overrides the envelope keys. Empty keys use the defaults.
+	Fields JSONFields
+	// OmitEmpty leaves out the error, data and meta members when they are
+	// empty.
+	OmitEmpty bool
+	// ErrorsArray writes the error as an array under the Errors key instead of
+	// a string.
+	ErrorsArray bool
+	// Unwrapped writes the bare data for successful responses. Errors are
+	// written as an object containing only the error member.
+	Unwrapped bool
+	// Envelope builds a custom value to marshal for each response. When set
+	// all other options are ignored.
+	Envelope func(Context, Response) interface{}
+	// PrettyDev indents the output in environments with PrettyOutput set,
+	// such as DEV.
+	PrettyDev bool
+}
 
 //JSONResponse - used by the JSONResponseAdapter to build the structure of the
 //default JSON response.
 type JSONResponse struct {
-	RequestID string                 `json:"requestId"`
-	Success   bool                   `json:"success"`
-	Error     string                 `json:"error"`
-	Data      interface{}            `json:"data"`
-	Meta      map[string]interface{} `json:"meta"`
+	RequestID string                 `json:"requestId" yaml:"requestId" msgpack:"requestId"`
+	Success   bool                   `json:"success" yaml:"success" msgpack:"success"`
+	Error     string                 `json:"error" yaml:"error" msgpack:"error"`
+	Data      interface{}            `json:"data" yaml:"data" msgpack:"data"`
+	Meta      map[string]interface{} `json:"meta" yaml:"meta" msgpack:"meta"`
+}
+
+// jsonMember is a single key/value pair of the JSON envelope.
+type jsonMember struct {
+	Key   string
+	Value interface{}
 }
 
 //Process - Process the response into JSON data.
 func (ra *JSONResponseAdapter) Process(c Context, r Response) ([]byte, error) {
-	rObj := JSONResponse{
-		RequestID: c.RequestID,
-		Meta:      r.Meta,
-		Data:      r.Data,
+	buf, err := ra.marshal(c, r)
+	if err != nil {
+		return nil, err
+	}
+	if ra.PrettyDev && c.Environment().PrettyOutput {
+		var out bytes.Buffer
+		if err := json.Indent(&out, buf, "", "  "); err != nil {
+			return nil, err
+		}
+		return out.Bytes(), nil
+	}
+	return buf, nil
+}
+
+func (ra *JSONResponseAdapter) marshal(c Context, r Response) ([]byte, error) {
+	if ra.Envelope != nil {
+		return json.Marshal(ra.Envelope(c, r))
+	}
+	fields := ra.fields()
+	if ra.Unwrapped {
+		if r.Error == nil {
+			return json.Marshal(r.Data)
+		}
+		return marshalMembers([]jsonMember{ra.errorMember(fields, r.Error)})
+	}
+
+	members := []jsonMember{
+		{fields.RequestID, c.RequestID},
+		{fields.Success, r.Error == nil},
 	}
 	if r.Error != nil {
-		rObj.Success = false
-		rObj.Error = r.Error.Error()
-	} else {
-		rObj.Success = true
+		members = append(members, ra.errorMember(fields, r.Error))
+	} else if !ra.OmitEmpty {
+		members = append(members, jsonMember{fields.Error, ""})
+	}
+	if r.Data != nil || !ra.OmitEmpty {
+		members = append(members, jsonMember{fields.Data, r.Data})
+	}
+	if len(r.Meta) > 0 || !ra.OmitEmpty {
+		members = append(members, jsonMember{fields.Meta, r.Meta})
+	}
+	return marshalMembers(members)
+}
+
+func (ra *JSONResponseAdapter) errorMember(fields JSONFields, err error) jsonMember {
+	if ra.ErrorsArray {
+		return jsonMember{fields.Errors, []string{err.Error()}}
+	}
+	return jsonMember{fields.Error, err.Error()}
+}
+
+// fields returns the envelope keys with defaults filled in.
+func (ra *JSONResponseAdapter) fields() JSONFields {
+	f := ra.Fields
+	if f.RequestID == "" {
+		f.RequestID = DefaultJSONFields.RequestID
+	}
+	if f.Success == "" {
+		f.Success = DefaultJSONFields.Success
+	}
+	if f.Error == "" {
+		f.Error = DefaultJSONFields.Error
+	}
+	if f.Errors == "" {
+		f.Errors = DefaultJSONFields.Errors
+	}
+	if f.Data == "" {
+		f.Data = DefaultJSONFields.Data
+	}
+	if f.Meta == "" {
+		f.Meta = DefaultJSONFields.Meta
+	}
+	return f
+}
+
+// marshalMembers writes the members as a JSON object keeping their order.
+func marshalMembers(members []jsonMember) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, m := range members {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(m.Key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(m.Value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
 	}
-	return json.Marshal(rObj)
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
 }
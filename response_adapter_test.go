@@ -0,0 +1,93 @@
+package celerity
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestJSONResponseAdapter(t *testing.T) {
+	c := NewContext()
+	c.RequestID = "123"
+	ok := c.R(map[string]string{"name": "alice"})
+	failed := c.Error(400, errors.New("bad request"))
+
+	tests := []struct {
+		Name     string
+		Adapter  *JSONResponseAdapter
+		Response Response
+		Expected string
+	}{
+		{
+			Name:     "default",
+			Adapter:  &JSONResponseAdapter{},
+			Response: ok,
+			Expected: `{"requestId":"123","success":true,"error":"","data":{"name":"alice"},"meta":{}}`,
+		},
+		{
+			Name:     "snake case",
+			Adapter:  &JSONResponseAdapter{Fields: SnakeCaseJSONFields},
+			Response: ok,
+			Expected: `{"request_id":"123","success":true,"error":"","data":{"name":"alice"},"meta":{}}`,
+		},
+		{
+			Name:     "omit empty",
+			Adapter:  &JSONResponseAdapter{OmitEmpty: true},
+			Response: ok,
+			Expected: `{"requestId":"123","success":true,"data":{"name":"alice"}}`,
+		},
+		{
+			Name:     "errors array",
+			Adapter:  &JSONResponseAdapter{OmitEmpty: true, ErrorsArray: true},
+			Response: failed,
+			Expected: `{"requestId":"123","success":false,"errors":["bad request"]}`,
+		},
+		{
+			Name:     "unwrapped",
+			Adapter:  &JSONResponseAdapter{Unwrapped: true},
+			Response: ok,
+			Expected: `{"name":"alice"}`,
+		},
+		{
+			Name:     "unwrapped error",
+			Adapter:  &JSONResponseAdapter{Unwrapped: true},
+			Response: failed,
+			Expected: `{"error":"bad request"}`,
+		},
+		{
+			Name: "envelope",
+			Adapter: &JSONResponseAdapter{
+				Envelope: func(c Context, r Response) interface{} {
+					return map[string]interface{}{"result": r.Data}
+				},
+			},
+			Response: ok,
+			Expected: `{"result":{"name":"alice"}}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			buf, err := test.Adapter.Process(c, test.Response)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			if string(buf) != test.Expected {
+				t.Errorf("output was %s", string(buf))
+			}
+		})
+	}
+
+	t.Run("pretty", func(t *testing.T) {
+		adapter := &JSONResponseAdapter{Unwrapped: true, PrettyDev: true}
+		c.Env = DEV
+		buf, _ := adapter.Process(c, ok)
+		if string(buf) != "{\n  \"name\": \"alice\"\n}" {
+			t.Errorf("output was %s", string(buf))
+		}
+		c.Env = PROD
+		buf, _ = adapter.Process(c, ok)
+		if string(buf) != `{"name":"alice"}` {
+			t.Errorf("output was %s", string(buf))
+		}
+	})
+}
@@ -2,11 +2,9 @@ package celerity
 
 import (
 	"fmt"
-	"io"
 	"net/http"
 	"os"
 	"path/filepath"
-	"strconv"
 	"strings"
 )
 
@@ -134,6 +132,7 @@ func (r *BasicRoute) String() string {
 type LocalFileRoute struct {
 	Path      RoutePath
 	LocalPath string
+	Config    StaticConfig
 }
 
 // Match checks if the incoming path matches the route path and if the local
@@ -141,7 +140,7 @@ type LocalFileRoute struct {
 func (l *LocalFileRoute) Match(method string, path string) (bool, string) {
 	ok, xtra := l.Path.Match(path)
 	fs := FSAdapter.RootPath(filepath.Dir(l.LocalPath))
-	if !ok || method != GET || xtra != "" {
+	if !ok || (method != GET && method != HEAD) || xtra != "" {
 		return false, path
 	}
 	fname := "/" + filepath.Base(l.LocalPath)
@@ -156,7 +155,7 @@ func (l *LocalFileRoute) Handle(c Context) Response {
 	fname := "/" + filepath.Base(l.LocalPath)
 	fpath := filepath.Dir(l.LocalPath)
 
-	serveFile(c.Writer, fpath, fname)
+	serveFile(c, fpath, fname, l.Config)
 	return Response{Handled: true}
 }
 
@@ -171,20 +170,28 @@ func (l *LocalFileRoute) RoutePath() RoutePath {
 type LocalPathRoute struct {
 	Path      RoutePath
 	LocalPath string
+	Config    StaticConfig
 }
 
-// Match checks if a file exists under the local path
+// Match checks if a file exists under the local path. Directories match when
+// the route's config serves an index file or a directory listing for them.
 func (l *LocalPathRoute) Match(method string, path string) (bool, string) {
 	fs := FSAdapter.RootPath(l.LocalPath)
+	if method != GET && method != HEAD {
+		return false, path
+	}
 	if len(path) < len(l.Path) {
 		return false, path
 	}
+	if ok, _ := l.Path.Match(path); !ok {
+		return false, path
+	}
 	fname := "/" + path[len(l.Path):]
 	stat, err := fs.Stat(fname)
 	if err != nil {
 		return false, path
 	}
-	if stat.Mode().IsDir() {
+	if stat.Mode().IsDir() && !l.Config.servesDirectory(fs, fname) {
 		return false, path
 	}
 	return true, ""
@@ -194,7 +201,11 @@ func (l *LocalPathRoute) Match(method string, path string) (bool, string) {
 func (l *LocalPathRoute) Handle(c Context) Response {
 	fname := c.ScopedPath[len(l.Path):]
 	fpath := l.LocalPath
-	serveFile(c.Writer, fpath, fname)
+	fs := FSAdapter.RootPath(fpath)
+	if stat, err := fs.Stat("/" + fname); err == nil && stat.IsDir() {
+		return serveDirectory(c, fpath, fname, l.Config)
+	}
+	serveFile(c, fpath, fname, l.Config)
 	return Response{Handled: true}
 }
 
@@ -203,40 +214,80 @@ func (l *LocalPathRoute) RoutePath() RoutePath {
 	return l.Path
 }
 
-func serveFile(w http.ResponseWriter, froot, fpath string) {
-	fs := FSAdapter.RootPath(froot)
-	f, err := fs.Open(fpath)
-	if os.IsNotExist(err) {
-		w.WriteHeader(404)
-		w.Write([]byte("The file does not exists"))
-		return
+// SPARoute serves a single page application. Files that exist under the local
+// path are served directly. Any other GET request that accepts HTML is
+// answered with the index file so client side routes can be deep linked.
+//
+// SPA routes are fallbacks. They are only tried after every other route and
+// scope in the same scope failed to match the request.
+type SPARoute struct {
+	Path      RoutePath
+	LocalPath string
+	Index     string
+	Config    StaticConfig
+}
 
+// Match checks if the path falls under the route path.
+func (r *SPARoute) Match(method string, path string) (bool, string) {
+	if method != GET && method != HEAD {
+		return false, path
 	}
-	if err != nil {
-		w.WriteHeader(500)
-		w.Write([]byte(err.Error()))
-		return
+	if len(path) < len(r.Path) {
+		return false, path
+	}
+	if ok, _ := r.Path.Match(path); !ok {
+		return false, path
 	}
+	return true, ""
+}
 
-	fileHeader := make([]byte, 512)
-	f.Read(fileHeader)
-	fstat, err := f.Stat()
-	if err != nil {
-		w.WriteHeader(500)
-		w.Write([]byte(err.Error()))
-		return
-	}
-	fsize := strconv.FormatInt(fstat.Size(), 10)
-	fname := filepath.Base(fpath)
-	contentType := http.DetectContentType(fileHeader)
-	switch filepath.Ext(fname) {
-	case ".css":
-		contentType = "text/css"
-	}
-
-	// w.Header().Set("Content-Disposition", "attachment; filename="+fname)
-	w.Header().Set("Content-Type", contentType)
-	w.Header().Set("Content-Length", fsize)
-	f.Seek(0, 0)
-	io.Copy(w, f)
+// Handle serves the requested file if it exists or the index file if the
+// request accepts HTML.
+func (r *SPARoute) Handle(c Context) Response {
+	fname := "/" + c.ScopedPath[len(r.Path):]
+	fs := FSAdapter.RootPath(r.LocalPath)
+	if stat, err := fs.Stat(fname); err == nil && !stat.IsDir() {
+		serveFile(c, r.LocalPath, fname, r.Config)
+		return Response{Handled: true}
+	}
+	if !acceptsHTML(c.Header("Accept")) {
+		return notFoundHandler(c)
+	}
+	config := r.Config
+	if config.cacheControl() == "" {
+		config.CacheControl = "no-cache"
+	}
+	serveFile(c, r.LocalPath, "/"+r.Index, config)
+	return Response{Handled: true}
+}
+
+// RoutePath returns the route path for the route.
+func (r *SPARoute) RoutePath() RoutePath {
+	return r.Path
+}
+
+// isFallback marks the route as a fallback route.
+func (r *SPARoute) isFallback() bool {
+	return true
+}
+
+// fallbackRoute is implemented by routes that should only be matched after
+// all other routes in a scope.
+type fallbackRoute interface {
+	isFallback() bool
+}
+
+// acceptsHTML checks if an Accept header explicitly lists an HTML media type.
+// Wildcards are ignored so asset and API requests are not answered with the
+// application's index page.
+func acceptsHTML(accept string) bool {
+	for _, entry := range ParseAccept(accept) {
+		if entry.Q <= 0 {
+			continue
+		}
+		if entry.Value == "text/html" || entry.Value == "application/xhtml+xml" {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,137 @@
+package celerity
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"github.com/5Sigma/vox"
+)
+
+// RouteInfo describes a registered route.
+type RouteInfo struct {
+	Method     string   `json:"method"`
+	Path       string   `json:"path"`
+	Type       string   `json:"type"`
+	Handler    string   `json:"handler"`
+	Middleware []string `json:"middleware"`
+}
+
+// Routes returns a description of every route in the router with its fully
+// qualified path and the middleware that wraps it. Routes are listed in the
+// order they are matched.
+func (r *Router) Routes() []RouteInfo {
+	return collectRoutes(r.Root, "/", []MiddlewareHandler{})
+}
+
+func collectRoutes(s *Scope, prefix string, pre []MiddlewareHandler) []RouteInfo {
+	prefix = joinRoutePath(prefix, string(s.Path))
+	pre = append(append([]MiddlewareHandler{}, pre...), s.PreMiddleware...)
+	middleware := []string{}
+	for _, mw := range append(pre, s.Middleware...) {
+		middleware = append(middleware, funcName(mw))
+	}
+	routes := []RouteInfo{}
+	for _, ss := range s.Scopes {
+		routes = append(routes, collectRoutes(ss, prefix, pre)...)
+	}
+	for _, rt := range s.Routes {
+		info := describeRoute(rt)
+		info.Path = joinRoutePath(prefix, string(rt.RoutePath()))
+		info.Middleware = middleware
+		routes = append(routes, info)
+	}
+	return routes
+}
+
+// describeRoute fills in the method, type and handler for a route.
+func describeRoute(rt Route) RouteInfo {
+	switch r := rt.(type) {
+	case *BasicRoute:
+		return RouteInfo{Method: r.Method, Type: "handler", Handler: funcName(r.Handler)}
+	case *LocalFileRoute:
+		return RouteInfo{Method: GET, Type: "file", Handler: r.LocalPath}
+	case *LocalPathRoute:
+		return RouteInfo{Method: GET, Type: "static", Handler: r.LocalPath}
+	case *SPARoute:
+		return RouteInfo{Method: GET, Type: "spa", Handler: r.LocalPath}
+	case *AssetRoute:
+		return RouteInfo{Method: GET, Type: "assets", Handler: r.LocalPath}
+	case *ChannelRoute:
+		return RouteInfo{Method: GET, Type: "channel", Handler: r.Channel.Name}
+	}
+	return RouteInfo{Method: "*", Type: fmt.Sprintf("%T", rt)}
+}
+
+// joinRoutePath joins a scope prefix and a route path.
+func joinRoutePath(prefix, p string) string {
+	if p == "" || p == "/" {
+		return prefix
+	}
+	return path.Join(prefix, p)
+}
+
+var closureSuffix = regexp.MustCompile(`(\.func\d+|\.\d+)+$`)
+
+// funcName returns a short name for a function, such as
+// "middleware.RequestLogger". Closures are named after the function that
+// created them.
+func funcName(f interface{}) string {
+	v := reflect.ValueOf(f)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return ""
+	}
+	fn := runtime.FuncForPC(v.Pointer())
+	if fn == nil {
+		return ""
+	}
+	name := fn.Name()
+	if idx := strings.LastIndex(name, "/"); idx >= 0 {
+		name = name[idx+1:]
+	}
+	name = strings.TrimSuffix(name, "-fm")
+	return closureSuffix.ReplaceAllString(name, "")
+}
+
+// filterRoutes returns the routes matching a method and a path prefix. The
+// prefix matches whole path segments, so /api matches /api and /api/users but
+// not /apiv2. Empty filters match every route.
+func filterRoutes(routes []RouteInfo, method, prefix string) []RouteInfo {
+	prefix = strings.TrimSuffix(prefix, "/")
+	filtered := []RouteInfo{}
+	for _, r := range routes {
+		if method != "" && !strings.EqualFold(r.Method, method) {
+			continue
+		}
+		if prefix != "" && r.Path != prefix && !strings.HasPrefix(r.Path, prefix+"/") {
+			continue
+		}
+		filtered = append(filtered, r)
+	}
+	return filtered
+}
+
+// printRoutes prints routes as a table or as JSON.
+func printRoutes(routes []RouteInfo, format string) error {
+	switch format {
+	case "json":
+		b, err := json.MarshalIndent(routes, "", "  ")
+		if err != nil {
+			return err
+		}
+		vox.Println(string(b))
+	case "table", "":
+		rows := [][]string{}
+		for _, r := range routes {
+			rows = append(rows, []string{r.Method, r.Path, r.Type, r.Handler, strings.Join(r.Middleware, ", ")})
+		}
+		printTable([]string{"METHOD", "PATH", "TYPE", "HANDLER", "MIDDLEWARE"}, rows)
+	default:
+		return fmt.Errorf("unknown format: %s", format)
+	}
+	return nil
+}
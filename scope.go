@@ -10,12 +10,13 @@ import (
 // Scope - A group of routes and subgroups used to represent the routing
 // structure for the serve.r
 type Scope struct {
-	server        *Server
-	Path          RoutePath
-	Scopes        []*Scope
-	Routes        []Route
-	Middleware    []MiddlewareHandler
-	PreMiddleware []MiddlewareHandler
+	server          *Server
+	responseAdapter ResponseAdapter
+	Path            RoutePath
+	Scopes          []*Scope
+	Routes          []Route
+	Middleware      []MiddlewareHandler
+	PreMiddleware   []MiddlewareHandler
 }
 
 // NewScope - Initializes a new scope
@@ -74,18 +75,45 @@ func (s *Scope) Route(method, path string, handler RouteHandler) Route {
 
 // ServePath serves static files at a filepath
 func (s *Scope) ServePath(path, staticpath string) {
+	s.ServePathWithConfig(path, staticpath, StaticConfig{})
+}
+
+// ServePathWithConfig serves static files at a filepath using the given
+// config.
+func (s *Scope) ServePathWithConfig(path, staticpath string, config StaticConfig) {
 	r := &LocalPathRoute{
 		Path:      RoutePath(path),
 		LocalPath: staticpath,
+		Config:    config,
 	}
 	s.Routes = append(s.Routes, r)
 }
 
 // ServeFile serves static files at a filepath
 func (s *Scope) ServeFile(path, localpath string) {
+	s.ServeFileWithConfig(path, localpath, StaticConfig{})
+}
+
+// ServeFileWithConfig serves a static file at a filepath using the given
+// config.
+func (s *Scope) ServeFileWithConfig(path, localpath string, config StaticConfig) {
 	r := &LocalFileRoute{
 		Path:      RoutePath(path),
 		LocalPath: localpath,
+		Config:    config,
+	}
+	s.Routes = append(s.Routes, r)
+}
+
+// ServeSPA serves a single page application from a filepath. Existing files
+// are served as static assets and other requests that accept HTML receive the
+// index file. Routes and scopes registered in the scope take precedence over
+// the application regardless of the order they are added.
+func (s *Scope) ServeSPA(path, staticpath, index string) {
+	r := &SPARoute{
+		Path:      RoutePath(path),
+		LocalPath: staticpath,
+		Index:     index,
 	}
 	s.Routes = append(s.Routes, r)
 }
@@ -101,30 +129,74 @@ func (s *Scope) Pre(mf ...MiddlewareHandler) {
 	s.PreMiddleware = append(s.PreMiddleware, mf...)
 }
 
+// SetResponseAdapter sets the adapter used to render responses for routes in
+// the scope. Child scopes inherit the adapter unless they set their own. Scopes
+// without an adapter use the server's ResponseAdapter.
+func (s *Scope) SetResponseAdapter(adapter ResponseAdapter) {
+	s.responseAdapter = adapter
+}
+
 // Match - Check if the scope can handle the incomming url
 func (s *Scope) Match(req *http.Request, path string) bool {
 	ok, rPath := s.Path.Match(path)
 	if !ok {
 		return false
 	}
-	for _, r := range s.Routes {
-		if ok, _ := r.Match(req.Method, rPath); ok {
+	for _, ss := range s.Scopes {
+		if ss.Match(req, rPath) {
 			return true
 		}
 	}
+	return s.matchRoute(req.Method, rPath) != nil
+}
+
+// matchRoute finds the route that handles a path in the scope. Fallback routes
+// are only considered when no other route matches and the path does not fall
+// under one of the scope's sub scopes.
+func (s *Scope) matchRoute(method, path string) Route {
+	var fallback Route
+	for _, r := range s.Routes {
+		if ok, _ := r.Match(method, path); !ok {
+			continue
+		}
+		if _, ok := r.(fallbackRoute); ok {
+			if fallback == nil {
+				fallback = r
+			}
+			continue
+		}
+		return r
+	}
+	if fallback == nil {
+		return nil
+	}
 	for _, ss := range s.Scopes {
-		if ss.Match(req, rPath) {
-			return true
+		if strings.Trim(string(ss.Path), "/") == "" {
+			continue
+		}
+		if ok, _ := ss.Path.Match(path); ok {
+			return nil
 		}
 	}
-	return false
+	return fallback
 }
 
 func notFoundHandler(c Context) Response {
 	return NewErrorResponse(http.StatusNotFound, "The requested resource was not found")
 }
 
-func (s *Scope) handleWithMiddleware(c Context, middleware []MiddlewareHandler) Response {
+func (s *Scope) handleWithMiddleware(c Context, middleware []MiddlewareHandler, adapter ResponseAdapter) Response {
+	if s.responseAdapter != nil {
+		adapter = s.responseAdapter
+	}
+	r := s.dispatch(c, middleware, adapter)
+	if r.Adapter == nil {
+		r.Adapter = adapter
+	}
+	return r
+}
+
+func (s *Scope) dispatch(c Context, middleware []MiddlewareHandler, adapter ResponseAdapter) Response {
 	ok, rPath := s.Path.Match(c.ScopedPath)
 	c.ScopedPath = rPath
 
@@ -143,20 +215,18 @@ func (s *Scope) handleWithMiddleware(c Context, middleware []MiddlewareHandler)
 
 		for _, ss := range s.Scopes {
 			if ss.Match(c.Request, c.ScopedPath) {
-				return ss.handleWithMiddleware(c, middleware)
+				return ss.handleWithMiddleware(c, middleware, adapter)
 			}
 		}
 
-		for _, r := range s.Routes {
-			if ok, _ := r.Match(c.Request.Method, c.ScopedPath); ok {
-				c.SetParams(r.RoutePath().GetURLParams(c.ScopedPath))
-				var h RouteHandler
-				h = r.Handle
-				for i := len(s.Middleware); i > 0; i-- {
-					h = s.Middleware[i-1](h)
-				}
-				return h(c)
+		if r := s.matchRoute(c.Request.Method, c.ScopedPath); r != nil {
+			c.SetParams(r.RoutePath().GetURLParams(c.ScopedPath))
+			var h RouteHandler
+			h = r.Handle
+			for i := len(s.Middleware); i > 0; i-- {
+				h = s.Middleware[i-1](h)
 			}
+			return h(c)
 		}
 
 		var h RouteHandler
@@ -180,13 +250,31 @@ func (s *Scope) Handle(c Context) (res Response) {
 	defer func() {
 		if r := recover(); r != nil {
 			res = c.Fail(fmt.Errorf("%v", r))
-			if c.Env == DEV {
+			if c.Environment().StackTraces {
 				stack := strings.Split(string(debug.Stack()), "\n")
 				res.Data = stack
 			}
+			res.Adapter = s.responseAdapterFor(c.Request, c.ScopedPath)
 		}
 	}()
-	return s.handleWithMiddleware(c, []MiddlewareHandler{})
+	return s.handleWithMiddleware(c, []MiddlewareHandler{}, nil)
+}
+
+// responseAdapterFor returns the response adapter of the innermost scope that
+// handles a path, or nil if the server's adapter should be used.
+func (s *Scope) responseAdapterFor(req *http.Request, path string) ResponseAdapter {
+	ok, rPath := s.Path.Match(path)
+	if ok {
+		for _, ss := range s.Scopes {
+			if ss.Match(req, rPath) {
+				if adapter := ss.responseAdapterFor(req, rPath); adapter != nil {
+					return adapter
+				}
+				break
+			}
+		}
+	}
+	return s.responseAdapter
 }
 
 func fixPath(p string) string {
@@ -335,3 +335,36 @@ func TestDeepestRoutePriority(t *testing.T) {
 		t.Errorf("should get api response, got %s", rStr)
 	}
 }
+
+func TestScopeResponseAdapter(t *testing.T) {
+	legacy := &JSONResponseAdapter{Unwrapped: true}
+	problem := &ProblemResponseAdapter{}
+
+	server := New()
+	api := server.Scope("/api")
+	api.SetResponseAdapter(problem)
+	api.Use(func(next RouteHandler) RouteHandler {
+		return func(c Context) Response {
+			return next(c).MetaValue("scope", "api")
+		}
+	})
+	users := api.Scope("/users")
+	users.GET("/:id", emptyHandler)
+	old := api.Scope("/v1")
+	old.SetResponseAdapter(legacy)
+	old.GET("/users", emptyHandler)
+	server.GET("/root", emptyHandler)
+
+	tests := map[string]ResponseAdapter{
+		"/api/users/1":  problem,
+		"/api/v1/users": legacy,
+		"/root":         nil,
+	}
+	for path, adapter := range tests {
+		req, _ := http.NewRequest(GET, path, nil)
+		r := server.Router.Root.Handle(RequestContext(req))
+		if r.Adapter != adapter {
+			t.Errorf("incorrect adapter for %s: %T", path, r.Adapter)
+		}
+	}
+}
@@ -2,8 +2,12 @@ package celerity
 
 import (
 	"bytes"
+	"context"
 	"io"
 	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/5Sigma/vox"
 )
@@ -14,6 +18,30 @@ type Server struct {
 	ResponseAdapter ResponseAdapter
 	Log             *vox.Vox
 	Channels        map[string]*Channel
+	Views           *ViewEngine
+	// ETags enables generated entity tags for GET responses.
+	ETags bool
+	// H2C accepts cleartext HTTP/2 connections when serving without TLS.
+	H2C bool
+	// DisableHTTP2 restricts the server to HTTP/1.x.
+	DisableHTTP2 bool
+	// Limits configures timeouts and connection limits.
+	Limits ServerLimits
+	// StartTimeout bounds the OnStart and OnReady hooks. It defaults to 30
+	// seconds.
+	StartTimeout  time.Duration
+	assets        []*AssetRoute
+	mimeTypes     map[string]string
+	httpServers   []*http.Server
+	adminRouter   *Router
+	startHooks    []func(context.Context) error
+	readyHooks    []func(context.Context) error
+	shutdownHooks []func(context.Context) error
+	started       bool
+	health        *Health
+	healthRouter  atomic.Value
+	shuttingDown  int32
+	mx            sync.Mutex
 }
 
 // NewServer - Initialize a new server
@@ -24,6 +52,7 @@ func NewServer() *Server {
 		Router:          router,
 		Log:             vox.New(),
 		Channels:        map[string]*Channel{},
+		Limits:          NewServerLimits(),
 	}
 	router.Root.server = svr
 	return svr
@@ -31,31 +60,68 @@ func NewServer() *Server {
 
 // ServeHTTP - Serves the HTTP request. Complies with http.Handler interface
 func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if hr, ok := s.healthRouter.Load().(*Router); ok && hr.Root.Match(r, r.URL.Path) {
+		s.handle(hr, w, r)
+		return
+	}
+	s.handle(s.Router, w, r)
+}
+
+// handle serves a request using the given router.
+func (s *Server) handle(router *Router, w http.ResponseWriter, r *http.Request) {
 	c := RequestContext(r)
 	c.Server = s
 	c.Writer = w
 	c.Log = s.Log
 	c.SetQueryParamsFromURL(r.URL)
-	resp := s.Router.Handle(c, r)
+	resp := router.Handle(c, r)
+
+	if resp.Writer != nil {
+		w = resp.Writer
+		c.Writer = w
+		if wc, ok := w.(io.Closer); ok {
+			defer wc.Close()
+		}
+	}
 
 	for k, vs := range c.Response.Header {
 		for _, v := range vs {
 			w.Header().Add(k, v)
 		}
 	}
+	for k, vs := range resp.Header {
+		w.Header()[k] = vs
+	}
 
 	switch true {
 	case resp.Handled:
 		return
 	case resp.IsRaw():
+		if s.notModified(c, resp, resp.Raw()) {
+			return
+		}
+		if resp.StatusCode != 0 {
+			w.WriteHeader(resp.StatusCode)
+		}
 		io.Copy(w, bytes.NewReader(resp.Raw()))
 	default:
-		buf, err := s.ResponseAdapter.Process(c, resp)
+		adapter := s.ResponseAdapter
+		if resp.Adapter != nil {
+			adapter = resp.Adapter
+		}
+		buf, err := adapter.Process(c, resp)
+		if err == ErrNotAcceptable {
+			w.WriteHeader(http.StatusNotAcceptable)
+			return
+		}
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
 
+		if s.notModified(c, resp, buf) {
+			return
+		}
 		w.WriteHeader(resp.StatusCode)
 		w.Write(buf)
 	}
@@ -71,9 +137,18 @@ func (s *Server) Use(mw MiddlewareHandler) {
 	s.Router.Root.Use(mw)
 }
 
-// Start the server.
+// Start the server. The host can be any address accepted by Listen. The start
+// hooks run before the address is bound. Start blocks until the server fails
+// or is stopped with Shutdown, in which case it returns nil.
 func (s *Server) Start(host string) error {
-	return http.ListenAndServe(host, s)
+	if err := s.runStartHooks(); err != nil {
+		return err
+	}
+	listeners, err := Listen(host)
+	if err != nil {
+		return err
+	}
+	return s.Serve(listeners...)
 }
 
 // Scope creates a new scope from the root scope
@@ -88,11 +163,29 @@ func (s *Server) ServePath(path, rootpath string) {
 	s.Router.Root.ServePath(path, rootpath)
 }
 
+// ServePathWithConfig serves a path of static files rooted at the path given
+// using the given config.
+func (s *Server) ServePathWithConfig(path, rootpath string, config StaticConfig) {
+	s.Router.Root.ServePathWithConfig(path, rootpath, config)
+}
+
 // ServeFile serves a static file at a given path
 func (s *Server) ServeFile(path, rootpath string) {
 	s.Router.Root.ServeFile(path, rootpath)
 }
 
+// ServeFileWithConfig serves a static file at a given path using the given
+// config.
+func (s *Server) ServeFileWithConfig(path, rootpath string, config StaticConfig) {
+	s.Router.Root.ServeFileWithConfig(path, rootpath, config)
+}
+
+// ServeSPA serves a single page application rooted at the path given. Requests
+// for client side routes receive the index file.
+func (s *Server) ServeSPA(path, rootpath, index string) {
+	s.Router.Root.ServeSPA(path, rootpath, index)
+}
+
 // Channel creates a socket channel at the given path
 func (s *Server) Channel(name, path string, h ChannelHandler) {
 	s.Router.Root.Channel(name, path, h)
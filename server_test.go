@@ -523,7 +523,7 @@ func TestStaticPathServing(t *testing.T) {
 			t.Errorf("Error requesting url: %s", err.Error())
 		}
 
-		if v := res.Header.Get("Content-Type"); v != "application/octet-stream" {
+		if v := res.Header.Get("Content-Type"); v != "text/plain; charset=utf-8" {
 			t.Errorf("content type was %s", v)
 		}
 
@@ -1,8 +1,15 @@
 package celerity
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
 
 	"github.com/5Sigma/vox"
 	"github.com/spf13/cobra"
@@ -11,6 +18,10 @@ import (
 
 var cfgFile string
 
+// envKeyReplacer maps nested configuration keys to environment variable names
+// so server.port can be set with SERVER_PORT.
+var envKeyReplacer = strings.NewReplacer(".", "_")
+
 func setupCLI(onRun func() *Server) *cobra.Command {
 
 	var rootCmd = &cobra.Command{
@@ -46,10 +57,54 @@ to quickly create a Cobra application.`,
 			)
 			vox.Println(banner)
 			server := onRun()
-			vox.PrintProperty("Bound IP", viper.GetString("host"))
-			vox.PrintProperty("Port", viper.GetString("port"))
-			err := server.Start(hostString)
-			vox.Error(err)
+			addrs := viper.GetStringSlice("listen")
+			if len(addrs) == 0 {
+				addrs = []string{hostString}
+				vox.PrintProperty("Bound IP", viper.GetString("host"))
+				vox.PrintProperty("Port", viper.GetString("port"))
+			}
+			for _, addr := range addrs {
+				vox.PrintProperty("Listen", addr)
+			}
+			server.Limits = limitsFromViper(viper.GetViper(), server.Limits)
+			if viper.GetBool("watch-config") {
+				WatchConfig()
+			}
+			if viper.GetBool("h2c") {
+				server.H2C = true
+			}
+			if !viper.GetBool("http2") {
+				server.DisableHTTP2 = true
+			}
+			serve := func() error {
+				if err := server.runStartHooks(); err != nil {
+					return err
+				}
+				listeners, err := listenAll(addrs)
+				if err != nil {
+					return err
+				}
+				if viper.GetString("tls-cert") != "" {
+					return server.ServeTLS(tlsConfigFromViper(), listeners...)
+				}
+				return server.Serve(listeners...)
+			}
+			start := serve
+			if viper.GetString("tls-cert") != "" {
+				vox.PrintProperty("TLS", viper.GetString("tls-cert"))
+			}
+			if addr := viper.GetString("admin-listen"); addr != "" {
+				vox.PrintProperty("Admin", addr)
+				start = func() error {
+					return server.serveWithAdmin(addr, serve)
+				}
+			}
+			quit := make(chan os.Signal, 1)
+			signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+			err := serveUntil(server, start, quit, viper.GetDuration("shutdown-timeout"))
+			if err != nil {
+				vox.Error(err)
+			}
 		},
 	}
 
@@ -61,64 +116,164 @@ to quickly create a Cobra application.`,
 	viper.BindPFlag("host", runCmd.PersistentFlags().Lookup("host"))
 	viper.SetDefault("host", "0.0.0.0")
 
-	runCmd.PersistentFlags().String("env", DEV, "Select the environment setup. Can be 'dev' or 'prod'")
-	viper.BindPFlag("env", runCmd.PersistentFlags().Lookup("env"))
-	viper.SetDefault("env", DEV)
+	runCmd.PersistentFlags().Duration("shutdown-timeout", 30*time.Second, "Time allowed for a graceful shutdown")
+	viper.BindPFlag("shutdown-timeout", runCmd.PersistentFlags().Lookup("shutdown-timeout"))
+	viper.SetDefault("shutdown-timeout", 30*time.Second)
+
+	runCmd.PersistentFlags().String("tls-cert", "", "TLS certificate file. Enables HTTPS when set")
+	viper.BindPFlag("tls-cert", runCmd.PersistentFlags().Lookup("tls-cert"))
+
+	runCmd.PersistentFlags().String("tls-key", "", "TLS private key file")
+	viper.BindPFlag("tls-key", runCmd.PersistentFlags().Lookup("tls-key"))
+
+	runCmd.PersistentFlags().String("tls-min-version", "1.2", "Minimum TLS version")
+	viper.BindPFlag("tls-min-version", runCmd.PersistentFlags().Lookup("tls-min-version"))
+	viper.SetDefault("tls-min-version", "1.2")
+
+	runCmd.PersistentFlags().StringSlice("tls-ciphers", []string{}, "Allowed TLS 1.2 cipher suites")
+	viper.BindPFlag("tls-ciphers", runCmd.PersistentFlags().Lookup("tls-ciphers"))
+
+	runCmd.PersistentFlags().String("tls-client-ca", "", "CA bundle used to require and verify client certificates")
+	viper.BindPFlag("tls-client-ca", runCmd.PersistentFlags().Lookup("tls-client-ca"))
+
+	runCmd.PersistentFlags().Bool("http2", true, "Enable HTTP/2")
+	viper.BindPFlag("http2", runCmd.PersistentFlags().Lookup("http2"))
+	viper.SetDefault("http2", true)
+
+	runCmd.PersistentFlags().Bool("h2c", false, "Accept cleartext HTTP/2 connections")
+	viper.BindPFlag("h2c", runCmd.PersistentFlags().Lookup("h2c"))
+
+	runCmd.PersistentFlags().StringSlice("listen", []string{}, "Addresses to listen on, such as tcp://:5000, unix:///run/app.sock?mode=0660 or fd://3. Overrides host and port")
+	viper.BindPFlag("listen", runCmd.PersistentFlags().Lookup("listen"))
+
+	runCmd.PersistentFlags().String("admin-listen", "", "Address for the admin listener serving the admin scope")
+	viper.BindPFlag("admin-listen", runCmd.PersistentFlags().Lookup("admin-listen"))
+
+	// Limit defaults come from the server's Limits when the run command starts.
+	limits := NewServerLimits()
+	runCmd.PersistentFlags().Duration("read-timeout", limits.ReadTimeout, "Maximum duration for reading a request")
+	viper.BindPFlag("read-timeout", runCmd.PersistentFlags().Lookup("read-timeout"))
+
+	runCmd.PersistentFlags().Duration("read-header-timeout", limits.ReadHeaderTimeout, "Maximum duration for reading request headers")
+	viper.BindPFlag("read-header-timeout", runCmd.PersistentFlags().Lookup("read-header-timeout"))
+
+	runCmd.PersistentFlags().Duration("write-timeout", limits.WriteTimeout, "Maximum duration for writing a response")
+	viper.BindPFlag("write-timeout", runCmd.PersistentFlags().Lookup("write-timeout"))
+
+	runCmd.PersistentFlags().Duration("idle-timeout", limits.IdleTimeout, "Time keep-alive connections wait for the next request")
+	viper.BindPFlag("idle-timeout", runCmd.PersistentFlags().Lookup("idle-timeout"))
+
+	runCmd.PersistentFlags().Int("max-header-bytes", limits.MaxHeaderBytes, "Maximum size of request headers")
+	viper.BindPFlag("max-header-bytes", runCmd.PersistentFlags().Lookup("max-header-bytes"))
+
+	runCmd.PersistentFlags().Int("max-connections", limits.MaxConnections, "Maximum concurrent connections per listener. 0 is unlimited")
+	viper.BindPFlag("max-connections", runCmd.PersistentFlags().Lookup("max-connections"))
+
+	runCmd.PersistentFlags().Bool("watch-config", false, "Reload the config file when it changes")
+	viper.BindPFlag("watch-config", runCmd.PersistentFlags().Lookup("watch-config"))
 
 	rootCmd.AddCommand(runCmd)
 
 	var routesCmd = &cobra.Command{
 		Use:   "routes",
 		Short: "List routes",
-		Long:  `Prints a list of all registered routes in the application.`,
-		Run: func(cmd *cobra.Command, args []string) {
+		Long: `Prints a list of all registered routes in the application with their full
+paths, methods, route type, handler and middleware.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
 			server := onRun()
-			vox.Println("All server routes:")
-			vox.Println("")
-			printScope(server.Router.Root)
-			vox.Println("")
-			vox.Println("")
+			method, _ := cmd.Flags().GetString("method")
+			prefix, _ := cmd.Flags().GetString("path")
+			format, _ := cmd.Flags().GetString("format")
+			routes := filterRoutes(server.Router.Routes(), method, prefix)
+			return printRoutes(routes, format)
 		},
 	}
+	routesCmd.Flags().String("method", "", "Only list routes for an HTTP method")
+	routesCmd.Flags().String("path", "", "Only list routes under a path prefix")
+	routesCmd.Flags().String("format", "table", "Output format. Can be 'table' or 'json'")
 	rootCmd.AddCommand(routesCmd)
 
+	var configCmd = &cobra.Command{
+		Use:   "config",
+		Short: "Show configuration",
+		Long: `Prints the effective configuration after merging the config file,
+environment variables and flags. Each setting shows where its value came from
+and secret values are redacted.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, _ := cmd.Flags().GetString("format")
+			return printConfig(effectiveConfig(cmd.Flags()), format)
+		},
+	}
+	configCmd.Flags().String("format", "table", "Output format. Can be 'table' or 'json'")
+	rootCmd.AddCommand(configCmd)
+
+	addCommands(rootCmd, onRun)
+
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is config.yaml)")
+
+	rootCmd.PersistentFlags().String("env", DEV, "Select the environment, such as dev, staging or prod")
+	viper.BindPFlag("env", rootCmd.PersistentFlags().Lookup("env"))
+	viper.SetDefault("env", DEV)
 	return rootCmd
 }
 
+// cliConfig enables environment variables and reads the config file once the
+// command line has been parsed, so the --config flag is respected.
 func cliConfig() {
-	cobra.OnInitialize(func() {
-		if cfgFile != "" {
-			// Use config file from the flag.
-			viper.SetConfigFile(cfgFile)
-		} else {
-			viper.AddConfigPath(".")
-			viper.SetConfigName(".config")
-		}
+	viper.SetEnvKeyReplacer(envKeyReplacer)
+	viper.AutomaticEnv() // read in environment variables that match
+	cobra.OnInitialize(readConfig)
+}
 
-		viper.AutomaticEnv() // read in environment variables that match
+// readConfig reads the config file given by the --config flag, or config.yaml
+// in the working directory, falling back to .config.yaml. The file for the
+// current environment, such as config.staging.yaml, is merged over it, even if
+// the base file does not exist. Environment variables and flags take
+// precedence over both.
+func readConfig() {
+	base := cfgFile
+	if base == "" {
+		base = findConfigFile(viper.GetString("env"))
+	}
+	if base == "" {
+		return
+	}
+	viper.SetConfigFile(base)
+	if err := viper.ReadInConfig(); err == nil {
+		fmt.Fprintln(os.Stderr, "Using config file:", base)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		vox.Error(err)
+		return
+	}
+	envFile, err := mergeEnvironmentConfig(viper.GetViper(), base, viper.GetString("env"))
+	if err != nil {
+		vox.Error(err)
+	} else if envFile != "" {
+		fmt.Fprintln(os.Stderr, "Using config file:", envFile)
+	}
+}
 
-		// If a config file is found, read it in.
-		if err := viper.ReadInConfig(); err == nil {
-			fmt.Println("Using config file:", viper.ConfigFileUsed())
+// findConfigFile looks for config or .config in the working directory with
+// any extension viper supports. If neither exists but the environment has a
+// config file, such as config.staging.yaml, the path config.yaml would have is
+// returned so the environment file can be merged on its own.
+func findConfigFile(env string) string {
+	for _, name := range []string{"config", ".config"} {
+		for _, ext := range viper.SupportedExts {
+			if _, err := os.Stat(name + "." + ext); err == nil {
+				return name + "." + ext
+			}
 		}
-
-	})
-
-	if cfgFile != "" {
-		// Use config file from the flag.
-		viper.SetConfigFile(cfgFile)
-	} else {
-		viper.AddConfigPath("")
-		viper.SetConfigName("config")
 	}
-
-	viper.AutomaticEnv() // read in environment variables that match
-
-	// If a config file is found, read it in.
-	if err := viper.ReadInConfig(); err == nil {
-		fmt.Println("Using config file:", viper.ConfigFileUsed())
+	if env == "" {
+		return ""
+	}
+	for _, ext := range viper.SupportedExts {
+		if _, err := os.Stat(environmentConfigFile("config."+ext, env)); err == nil {
+			return "config." + ext
+		}
 	}
+	return ""
 }
 
 // HandleCLI - Use the built in CLI handling for the server.
@@ -137,14 +292,16 @@ func HandleCLI(onRun func() *Server) error {
 	return nil
 }
 
-func printScope(s *Scope) {
-	vox.Println(vox.Yellow, "[SCOPE]", vox.ResetColor, " ", s.Path)
-
-	for _, ss := range s.Scopes {
-		printScope(ss)
+// printTable prints rows as aligned columns under a header.
+func printTable(header []string, rows [][]string) {
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
 	}
-
-	for _, r := range s.Routes {
-		vox.Println("\t", r)
+	w.Flush()
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		vox.Println(strings.TrimRight(line, " "))
 	}
 }
@@ -1,12 +1,13 @@
 package celerity
 
 import (
-	"fmt"
+	"encoding/json"
 	"os"
 	"strings"
 	"testing"
 
 	"github.com/5Sigma/vox"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
@@ -14,29 +15,88 @@ func EmptyRouteHandler(c Context) Response {
 	return c.R(nil)
 }
 
-func TestRoutesCommand(t *testing.T) {
+func authMiddleware() MiddlewareHandler {
+	return func(next RouteHandler) RouteHandler {
+		return next
+	}
+}
 
-	rootCmd := setupCLI(func() *Server {
+func TestRoutesCommand(t *testing.T) {
+	setup := func() *Server {
 		svr := New()
-
 		svr.GET("/test", EmptyRouteHandler)
+		api := svr.Scope("/api")
+		api.Use(authMiddleware())
+		api.POST("/users/:id", EmptyRouteHandler)
+		api.ServePath("/files", "public")
+		svr.Channel("events", "/events", func(client *SocketClient, e ChannelEvent) {})
 		return svr
-	})
-	pl := vox.Test()
-	rootCmd.SetArgs([]string{"routes"})
-	err := rootCmd.Execute()
-	if err != nil {
-		t.Fatalf("Error running routes command: %s", err.Error())
 	}
-	expected := fmt.Sprintf(`All server routes:
 
-%s[SCOPE]%s /
-	GET	/test
+	t.Run("table", func(t *testing.T) {
+		rootCmd := setupCLI(setup)
+		pl := vox.Test()
+		rootCmd.SetArgs([]string{"routes"})
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("Error running routes command: %s", err.Error())
+		}
+		expected := `METHOD  PATH            TYPE     HANDLER                     MIDDLEWARE
+POST    /api/users/:id  handler  celerity.EmptyRouteHandler  celerity.authMiddleware
+GET     /api/files      static   public                      celerity.authMiddleware
+GET     /test           handler  celerity.EmptyRouteHandler
+GET     /events         channel  events
+`
+		if v := strings.Join(pl.LogLines, ""); v != expected {
+			t.Errorf("incorrect output: \n'%s'\n'%s'", expected, v)
+		}
+	})
 
-`, vox.Yellow, vox.ResetColor) + "\n"
+	t.Run("json", func(t *testing.T) {
+		rootCmd := setupCLI(setup)
+		pl := vox.Test()
+		rootCmd.SetArgs([]string{"routes", "--format", "json", "--method", "get", "--path", "/api"})
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("Error running routes command: %s", err.Error())
+		}
+		routes := []RouteInfo{}
+		if err := json.Unmarshal([]byte(strings.Join(pl.LogLines, "")), &routes); err != nil {
+			t.Fatal(err.Error())
+		}
+		if len(routes) != 1 {
+			t.Fatalf("routes were %v", routes)
+		}
+		if r := routes[0]; r.Path != "/api/files" || r.Type != "static" || r.Method != GET {
+			t.Errorf("route was %v", r)
+		}
+	})
+}
 
-	if v := strings.Join(pl.LogLines, ""); v != expected {
-		t.Errorf("incorrect output: \n'%s'\n'%s'", expected, v)
+func TestFilterRoutes(t *testing.T) {
+	routes := []RouteInfo{
+		{Method: GET, Path: "/api"},
+		{Method: POST, Path: "/api/users"},
+		{Method: GET, Path: "/apiv2/users"},
+		{Method: GET, Path: "/"},
+	}
+	tests := []struct {
+		Method string
+		Prefix string
+		Paths  string
+	}{
+		{"", "", "/api /api/users /apiv2/users /"},
+		{"", "/api", "/api /api/users"},
+		{"", "/api/", "/api /api/users"},
+		{"get", "/api", "/api"},
+		{"", "/", "/api /api/users /apiv2/users /"},
+	}
+	for _, test := range tests {
+		paths := []string{}
+		for _, r := range filterRoutes(routes, test.Method, test.Prefix) {
+			paths = append(paths, r.Path)
+		}
+		if v := strings.Join(paths, " "); v != test.Paths {
+			t.Errorf("%s %s: routes were %s", test.Method, test.Prefix, v)
+		}
 	}
 }
 
@@ -48,3 +108,91 @@ func TestEnvironmentVariables(t *testing.T) {
 		t.Errorf("environment reading not setup: %s", v)
 	}
 }
+
+// resetCommands removes the application commands and flags added by a test.
+func resetCommands() {
+	commands = []Command{}
+	cliFlags = pflag.NewFlagSet("celerity", pflag.ContinueOnError)
+}
+
+func TestAddCommand(t *testing.T) {
+	defer resetCommands()
+	CLIFlags().String("database", "sqlite://app.db", "Database URL")
+	var (
+		gotServer *Server
+		gotArgs   []string
+		database  string
+		dryRun    bool
+	)
+	AddCommand(Command{
+		Use:   "migrate",
+		Short: "Run database migrations",
+		Flags: func(flags *pflag.FlagSet) {
+			flags.Bool("dry-run", false, "Print migrations without running them")
+		},
+		Run: func(server *Server, config *viper.Viper, args []string) error {
+			gotServer = server
+			gotArgs = args
+			database = config.GetString("database")
+			dryRun = config.GetBool("dry-run")
+			return nil
+		},
+	})
+	svr := New()
+	rootCmd := setupCLI(func() *Server { return svr })
+	rootCmd.SetArgs([]string{"migrate", "--database", "postgres://db", "--dry-run", "up"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("Error running migrate command: %s", err.Error())
+	}
+	if gotServer != svr {
+		t.Error("command did not receive the server")
+	}
+	if len(gotArgs) != 1 || gotArgs[0] != "up" {
+		t.Errorf("args were %v", gotArgs)
+	}
+	if database != "postgres://db" {
+		t.Errorf("persistent flag was not bound: %s", database)
+	}
+	if !dryRun {
+		t.Error("command flag was not bound")
+	}
+}
+
+func TestCommandFlagsDoNotShadow(t *testing.T) {
+	defer resetCommands()
+	defer viper.Reset()
+	ports := map[string]int{}
+	for _, name := range []string{"serve-a", "serve-b"} {
+		name := name
+		AddCommand(Command{
+			Use: name,
+			Flags: func(flags *pflag.FlagSet) {
+				flags.Int("port", 7000, "Port")
+			},
+			Run: func(server *Server, config *viper.Viper, args []string) error {
+				ports[name] = config.GetInt("port")
+				return nil
+			},
+		})
+	}
+	CLIFlags().String("host", "app-host", "Host")
+
+	rootCmd := setupCLI(New)
+	rootCmd.SetArgs([]string{"serve-a", "--port", "7001"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatal(err.Error())
+	}
+	if ports["serve-a"] != 7001 {
+		t.Errorf("flag of the running command was shadowed: %d", ports["serve-a"])
+	}
+
+	runCmd, _, err := rootCmd.Find([]string{"run"})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	runCmd.ParseFlags([]string{"--host", "127.0.0.1"})
+	bindFlags(runCmd, nil)
+	if v := viper.GetString("host"); v != "127.0.0.1" {
+		t.Errorf("app flag shadowed the run command's host: %s", v)
+	}
+}
@@ -0,0 +1,85 @@
+package celerity
+
+import (
+	"context"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// OnShutdown registers a function that is called when the server shuts down.
+// Hooks run in the order they were registered after requests, jobs and
+// channels have been drained. They should return once the context is done.
+func (s *Server) OnShutdown(f func(context.Context) error) {
+	s.mx.Lock()
+	s.shutdownHooks = append(s.shutdownHooks, f)
+	s.mx.Unlock()
+}
+
+// Shutdown gracefully stops the server. Readiness checks start failing
+// immediately and, if the health DrainDelay is set, requests continue to be
+// served for that long. It then stops accepting connections and waits for
+// in-flight requests to finish, waits for queued jobs, sends close frames to
+// all channel clients and finally runs the shutdown hooks. Channels are closed
+// after jobs so jobs can still broadcast to them.
+//
+// The context bounds how long Shutdown waits. If it expires the remaining
+// steps are still attempted and the context's error is returned.
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+//	defer cancel()
+//	server.Shutdown(ctx)
+func (s *Server) Shutdown(ctx context.Context) error {
+	atomic.StoreInt32(&s.shuttingDown, 1)
+	s.mx.Lock()
+	servers := s.httpServers
+	hooks := s.shutdownHooks
+	health := s.health
+	s.mx.Unlock()
+
+	var firstErr error
+	keep := func(err error) {
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	if health != nil && health.DrainDelay > 0 {
+		select {
+		case <-time.After(health.DrainDelay):
+		case <-ctx.Done():
+		}
+	}
+	for _, hs := range servers {
+		keep(hs.Shutdown(ctx))
+	}
+	keep(WaitForJobs(ctx))
+	for _, ch := range s.Channels {
+		ch.Close()
+	}
+	for _, f := range hooks {
+		keep(f(ctx))
+	}
+	return firstErr
+}
+
+// serveUntil starts the server using the start function and shuts it down
+// gracefully when a value is received on quit. The timeout bounds how long the
+// shutdown may take.
+func serveUntil(s *Server, start func() error, quit <-chan os.Signal, timeout time.Duration) error {
+	errs := make(chan error, 1)
+	go func() {
+		errs <- start()
+	}()
+	select {
+	case err := <-errs:
+		return err
+	case <-quit:
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := s.Shutdown(ctx); err != nil {
+		return err
+	}
+	return <-errs
+}
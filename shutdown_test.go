@@ -0,0 +1,147 @@
+package celerity
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+var slowJobDone int32
+
+type SlowJob struct{}
+
+func (job SlowJob) Run() error {
+	time.Sleep(100 * time.Millisecond)
+	atomic.StoreInt32(&slowJobDone, 1)
+	return nil
+}
+
+// freeAddr returns a local address that is available for listening.
+func freeAddr(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer l.Close()
+	return l.Addr().String()
+}
+
+// waitForServer waits until a server accepts connections on the address.
+func waitForServer(t *testing.T, addr string) {
+	for i := 0; i < 100; i++ {
+		if conn, err := net.Dial("tcp", addr); err == nil {
+			conn.Close()
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("server did not start on %s", addr)
+}
+
+// BroadcastJob broadcasts to a channel after a delay.
+type BroadcastJob struct {
+	Channel *Channel
+}
+
+func (job BroadcastJob) Run() error {
+	time.Sleep(100 * time.Millisecond)
+	job.Channel.BroadcastRaw([]byte("job done"))
+	return nil
+}
+
+func TestShutdown(t *testing.T) {
+	server := New()
+	server.GET("/slow", func(c Context) Response {
+		time.Sleep(200 * time.Millisecond)
+		return c.R("done")
+	})
+	server.Channel("events", "/events", func(client *SocketClient, e ChannelEvent) {})
+	hooks := []string{}
+	server.OnShutdown(func(ctx context.Context) error {
+		hooks = append(hooks, "first")
+		return nil
+	})
+	server.OnShutdown(func(ctx context.Context) error {
+		hooks = append(hooks, "second")
+		return errors.New("hook failed")
+	})
+
+	addr := freeAddr(t)
+	quit := make(chan os.Signal, 1)
+	served := make(chan error, 1)
+	go func() {
+		served <- serveUntil(server, func() error { return server.Start(addr) }, quit, 5*time.Second)
+	}()
+	waitForServer(t, addr)
+
+	ws, _, err := websocket.DefaultDialer.Dial("ws://"+addr+"/events", nil)
+	if err != nil {
+		t.Fatal("dial:", err)
+	}
+	defer ws.Close()
+
+	body := make(chan string, 1)
+	go func() {
+		res, err := http.Get("http://" + addr + "/slow")
+		if err != nil {
+			body <- err.Error()
+			return
+		}
+		defer res.Body.Close()
+		b, _ := ioutil.ReadAll(res.Body)
+		body <- string(b)
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	atomic.StoreInt32(&slowJobDone, 0)
+	RunNow(SlowJob{})
+	RunNow(BroadcastJob{server.Channels["events"]})
+	quit <- syscall.SIGTERM
+
+	if err := <-served; err == nil || err.Error() != "hook failed" {
+		t.Errorf("shutdown error was %v", err)
+	}
+	if b := <-body; b == "" || b[0] != '{' {
+		t.Errorf("in-flight request was not drained: %s", b)
+	}
+	if atomic.LoadInt32(&slowJobDone) != 1 {
+		t.Error("queued job did not finish")
+	}
+	if len(hooks) != 2 || hooks[0] != "first" || hooks[1] != "second" {
+		t.Errorf("hooks were %v", hooks)
+	}
+	ws.SetReadDeadline(time.Now().Add(time.Second))
+	_, msg, err := ws.ReadMessage()
+	if err != nil || string(msg) != "job done" {
+		t.Errorf("job broadcast was not delivered before close: %s %v", string(msg), err)
+	}
+	_, _, err = ws.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Errorf("websocket was not closed: %v", err)
+	}
+	if _, err := net.Dial("tcp", addr); err == nil {
+		t.Error("server still accepting connections")
+	}
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	server := New()
+	server.OnShutdown(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Errorf("error was %v", err)
+	}
+}
@@ -42,6 +42,9 @@ const (
 
 	// Maximum message size allowed from peer.
 	maxMessageSize = 512
+
+	// Number of messages queued for a client before new ones are dropped.
+	sendBufferSize = 256
 )
 
 // ChannelEventType is an event type for websocket connections. Such as joining,
@@ -88,9 +91,11 @@ type Channel struct {
 	connect    chan *SocketClient
 	disconnect chan *SocketClient
 	message    chan *SocketMessage
+	shutdown   chan chan struct{}
 	Handler    ChannelHandler
 	upgrader   websocket.Upgrader
 	Rooms      map[string]*ChannelRoom
+	mx         sync.RWMutex
 }
 
 // NewChannel Undescribed
@@ -105,6 +110,7 @@ func NewChannel(h ChannelHandler) *Channel {
 		connect:    make(chan *SocketClient),
 		disconnect: make(chan *SocketClient),
 		message:    make(chan *SocketMessage),
+		shutdown:   make(chan chan struct{}),
 		Handler:    h,
 		upgrader:   upgrader,
 		Rooms:      map[string]*ChannelRoom{},
@@ -128,7 +134,9 @@ func (ch *Channel) Open() {
 		for {
 			select {
 			case client := <-ch.connect:
+				ch.mx.Lock()
 				ch.Clients[client] = true
+				ch.mx.Unlock()
 				evt := ChannelEvent{
 					Event: ChannelEvents.Connect,
 					Data:  []byte{},
@@ -140,13 +148,10 @@ func (ch *Channel) Open() {
 					Data:  []byte{},
 				}
 				ch.Handler(client, evt)
-				if _, ok := ch.Clients[client]; ok {
-					delete(ch.Clients, client)
-					close(client.send)
-				}
-				for _, r := range client.Rooms {
-					r.Remove(client)
-				}
+				ch.mx.Lock()
+				delete(ch.Clients, client)
+				ch.mx.Unlock()
+				client.close()
 
 			case msg := <-ch.message:
 				evt := ChannelEvent{
@@ -154,27 +159,62 @@ func (ch *Channel) Open() {
 					Data:  msg.Message,
 				}
 				ch.Handler(msg.Client, evt)
+			case done := <-ch.shutdown:
+				ch.mx.Lock()
+				clients := ch.Clients
+				ch.Clients = map[*SocketClient]bool{}
+				ch.mx.Unlock()
+				for client := range clients {
+					client.close()
+				}
+				close(done)
 			}
 		}
 	}()
 }
 
+// Close sends a close frame to every client connected to the channel and
+// disconnects them. The clients are removed from their rooms and anything sent
+// to them afterwards is dropped. The channel must be open.
+func (ch *Channel) Close() {
+	done := make(chan struct{})
+	ch.shutdown <- done
+	<-done
+}
+
+// ClientCount returns the number of clients connected to the channel.
+func (ch *Channel) ClientCount() int {
+	ch.mx.RLock()
+	defer ch.mx.RUnlock()
+	return len(ch.Clients)
+}
+
 // Broadcast send an event to all clients in the channel
 func (ch *Channel) Broadcast(msg interface{}) {
-	for c, connected := range ch.Clients {
-		if connected {
-			c.Send(msg)
-		}
+	for _, c := range ch.connectedClients() {
+		c.Send(msg)
 	}
 }
 
 // BroadcastRaw send an event to all clients in the channel
 func (ch *Channel) BroadcastRaw(msg []byte) {
+	for _, c := range ch.connectedClients() {
+		c.SendRaw(msg)
+	}
+}
+
+// connectedClients returns a snapshot of the connected clients so messages
+// can be sent without holding the channel lock.
+func (ch *Channel) connectedClients() []*SocketClient {
+	ch.mx.RLock()
+	defer ch.mx.RUnlock()
+	clients := make([]*SocketClient, 0, len(ch.Clients))
 	for c, connected := range ch.Clients {
 		if connected {
-			c.SendRaw(msg)
+			clients = append(clients, c)
 		}
 	}
+	return clients
 }
 
 // SocketClient wraps an actual websocket connection. It provides the
@@ -186,6 +226,8 @@ type SocketClient struct {
 	send    chan []byte
 	conn    *websocket.Conn
 	Rooms   []*ChannelRoom
+	closed  bool
+	mx      sync.Mutex
 }
 
 // NewSocketClient creates a new client to control websocket connections
@@ -196,7 +238,7 @@ func NewSocketClient(c Context, ch *Channel, conn *websocket.Conn) *SocketClient
 		ID:      id,
 		Context: c,
 		conn:    conn,
-		send:    make(chan []byte),
+		send:    make(chan []byte, sendBufferSize),
 		Rooms:   []*ChannelRoom{},
 	}
 	ch.connect <- client
@@ -225,9 +267,38 @@ func (c *SocketClient) SendString(msg string) {
 	c.SendRaw([]byte(msg))
 }
 
-// SendRaw sends bytes to the client
+// SendRaw queues bytes to be sent to the client. It never blocks: messages
+// sent after the client has been disconnected, or while its queue is full
+// because it is not reading fast enough, are dropped.
 func (c *SocketClient) SendRaw(msg []byte) {
-	c.send <- append(msg)
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	if c.closed {
+		return
+	}
+	select {
+	case c.send <- msg:
+	default:
+		if c.Context.Log != nil {
+			c.Context.Log.Errorf("dropped message for slow socket client %d", c.ID)
+		}
+	}
+}
+
+// close removes the client from its rooms and closes its send channel, which
+// makes the write loop send a close frame. It is safe to call more than once.
+func (c *SocketClient) close() {
+	rooms := append([]*ChannelRoom{}, c.Rooms...)
+	for _, r := range rooms {
+		r.Remove(c)
+	}
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	if c.closed {
+		return
+	}
+	c.closed = true
+	close(c.send)
 }
 
 func (c *SocketClient) readLoop() {
@@ -266,7 +337,9 @@ func (c *SocketClient) writeLoop() {
 		case message, ok := <-c.send:
 			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
 			if !ok {
-				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				c.conn.WriteMessage(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseGoingAway, ""))
+				return
 			}
 			w, err := c.conn.NextWriter(websocket.TextMessage)
 			if err != nil {
@@ -338,18 +411,25 @@ func (r *ChannelRoom) Remove(c *SocketClient) {
 
 // Broadcast send an event to all clients in the channel
 func (r *ChannelRoom) Broadcast(msg interface{}) {
-	for _, c := range r.Clients {
+	for _, c := range r.members() {
 		c.Send(msg)
 	}
 }
 
 // BroadcastRaw send an event to all clients in the channel
 func (r *ChannelRoom) BroadcastRaw(msg []byte) {
-	for _, c := range r.Clients {
+	for _, c := range r.members() {
 		c.SendRaw(msg)
 	}
 }
 
+// members returns a snapshot of the clients in the room.
+func (r *ChannelRoom) members() []*SocketClient {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	return append([]*SocketClient{}, r.Clients...)
+}
+
 // ChannelRoute is a server route that serves a websocket connection
 type ChannelRoute struct {
 	Path    RoutePath
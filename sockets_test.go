@@ -5,6 +5,7 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"testing"
+	"time"
 
 	"github.com/gorilla/websocket"
 )
@@ -146,6 +147,70 @@ func TestSockets(t *testing.T) {
 	})
 }
 
+func TestChannelClose(t *testing.T) {
+	server := New()
+	connected := make(chan *SocketClient, 1)
+	server.Channel("rooms", "/rooms", func(client *SocketClient, e ChannelEvent) {
+		if e.Event == ChannelEvents.Connect {
+			client.Channel().Room("lobby").Add(client)
+			connected <- client
+		}
+	})
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+ts.URL[4:]+"/rooms", nil)
+	if err != nil {
+		t.Fatal("dial:", err)
+	}
+	defer ws.Close()
+	client := <-connected
+
+	ch := server.Channels["rooms"]
+	ch.Close()
+	if l := len(ch.Room("lobby").Clients); l != 0 {
+		t.Errorf("room should have 0 clients, has %d", l)
+	}
+	if l := len(client.Rooms); l != 0 {
+		t.Errorf("client should have 0 rooms, has %d", l)
+	}
+	if n := ch.ClientCount(); n != 0 {
+		t.Errorf("channel should have 0 clients, has %d", n)
+	}
+	ch.BroadcastRaw([]byte("after close"))
+	ch.Room("lobby").BroadcastRaw([]byte("after close"))
+	client.SendString("after close")
+	ws.SetReadDeadline(time.Now().Add(time.Second))
+	if _, _, err := ws.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Errorf("websocket was not closed: %v", err)
+	}
+}
+
+func TestBroadcastSlowClient(t *testing.T) {
+	ch := NewChannel(emptyChannelHandler)
+	slow := &SocketClient{ID: 1, ch: ch, send: make(chan []byte, sendBufferSize)}
+	ch.Clients[slow] = true
+	room := ch.Room("lobby")
+	room.Add(slow)
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < sendBufferSize; i++ {
+			ch.BroadcastRaw([]byte("channel"))
+			room.BroadcastRaw([]byte("room"))
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("broadcast blocked on a client that is not reading")
+	}
+	if l := len(slow.send); l != sendBufferSize {
+		t.Errorf("queued %d messages", l)
+	}
+}
+
 func TestRoomRemove(t *testing.T) {
 	c1 := &SocketClient{ID: 1}
 	c2 := &SocketClient{ID: 2}
@@ -0,0 +1,237 @@
+package celerity
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+// StaticConfig configures how static files are served. It can be passed to
+// ServePathWithConfig and ServeFileWithConfig.
+type StaticConfig struct {
+	// MaxAge sets a public Cache-Control max-age for served files.
+	MaxAge time.Duration
+	// CacheControl sets the Cache-Control header verbatim. It takes precedence
+	// over MaxAge.
+	CacheControl string
+	// Index is the file served when a directory is requested, such as
+	// "index.html".
+	Index string
+	// RedirectTrailingSlash redirects requests for directories to the same
+	// path ending with a slash.
+	RedirectTrailingSlash bool
+	// Listing renders the contents of directories without an index file.
+	Listing DirectoryListing
+	// Precompressed serves a gzipped sibling of a file, such as app.js.gz for
+	// app.js, to clients that accept gzip.
+	Precompressed bool
+}
+
+// DirectoryListing is the format used to list the contents of directories.
+type DirectoryListing int
+
+const (
+	// NoListing disables directory listings.
+	NoListing DirectoryListing = iota
+	// HTMLListing renders directory listings as an HTML page.
+	HTMLListing
+	// JSONListing renders directory listings through the response adapter.
+	JSONListing
+)
+
+// DirectoryEntry is a single file in a directory listing.
+type DirectoryEntry struct {
+	Name     string    `json:"name"`
+	Size     int64     `json:"size"`
+	Dir      bool      `json:"dir"`
+	Modified time.Time `json:"modified"`
+}
+
+var listingTemplate = template.Must(template.New("listing").Parse(`<!DOCTYPE html>
+<html>
+<head><title>Index of {{ .Path }}</title></head>
+<body>
+<h1>Index of {{ .Path }}</h1>
+<ul>
+{{- range .Entries }}
+<li><a href="{{ .Href }}">{{ .Name }}{{ if .Dir }}/{{ end }}</a></li>
+{{- end }}
+</ul>
+</body>
+</html>
+`))
+
+// servesDirectory checks if a directory can be served using the config.
+func (sc StaticConfig) servesDirectory(fs afero.Fs, dir string) bool {
+	if sc.Listing != NoListing {
+		return true
+	}
+	if sc.Index == "" {
+		return false
+	}
+	stat, err := fs.Stat(path.Join(dir, sc.Index))
+	return err == nil && !stat.IsDir()
+}
+
+// cacheControl returns the Cache-Control header value for the config.
+func (sc StaticConfig) cacheControl() string {
+	if sc.CacheControl != "" {
+		return sc.CacheControl
+	}
+	if sc.MaxAge > 0 {
+		return fmt.Sprintf("public, max-age=%d", int(sc.MaxAge.Seconds()))
+	}
+	return ""
+}
+
+// serveFile writes a file from the file system adapter to the response. Range
+// requests, conditional requests and HEAD requests are handled using
+// http.ServeContent. The file's modification time is sent as Last-Modified and
+// a strong ETag is generated from its size and modification time so it can be
+// used with If-Range.
+func serveFile(c Context, froot, fpath string, config StaticConfig) {
+	w := c.Writer
+	fs := FSAdapter.RootPath(froot)
+	f, err := fs.Open(fpath)
+	if os.IsNotExist(err) {
+		w.WriteHeader(404)
+		w.Write([]byte("The file does not exists"))
+		return
+
+	}
+	if err != nil {
+		w.WriteHeader(500)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	defer f.Close()
+
+	fileHeader := make([]byte, 512)
+	n, _ := io.ReadFull(f, fileHeader)
+	fstat, err := f.Stat()
+	if err != nil {
+		w.WriteHeader(500)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	fname := filepath.Base(fpath)
+	w.Header().Set("Content-Type", c.Server.contentType(fname, fileHeader[:n]))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	f.Seek(0, 0)
+
+	var content io.ReadSeeker = f
+	etagSuffix := ""
+	if config.Precompressed {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if ParseAccept(c.Header("Accept-Encoding")).Accepts("gzip") {
+			if gz, gzstat, err := openFile(fs, fpath+".gz"); err == nil {
+				defer gz.Close()
+				w.Header().Set("Content-Encoding", "gzip")
+				content, fstat, etagSuffix = gz, gzstat, "-gz"
+			}
+		}
+	}
+
+	if w.Header().Get("ETag") == "" {
+		w.Header().Set("ETag", fmt.Sprintf(`"%x-%x%s"`, fstat.Size(), fstat.ModTime().UnixNano(), etagSuffix))
+	}
+	if cc := config.cacheControl(); cc != "" {
+		w.Header().Set("Cache-Control", cc)
+	}
+	http.ServeContent(w, c.Request, fname, fstat.ModTime(), content)
+}
+
+// openFile opens a regular file and returns it with its file info.
+func openFile(fs afero.Fs, fpath string) (afero.File, os.FileInfo, error) {
+	f, err := fs.Open(fpath)
+	if err != nil {
+		return nil, nil, err
+	}
+	stat, err := f.Stat()
+	if err != nil || stat.IsDir() {
+		f.Close()
+		return nil, nil, os.ErrNotExist
+	}
+	return f, stat, nil
+}
+
+// serveDirectory serves the index file of a directory or a listing of its
+// contents. Requests without a trailing slash are redirected if the config
+// asks for it.
+func serveDirectory(c Context, froot, dir string, config StaticConfig) Response {
+	urlPath := c.Request.URL.Path
+	if !strings.HasSuffix(urlPath, "/") && config.RedirectTrailingSlash {
+		target := urlPath + "/"
+		if c.Request.URL.RawQuery != "" {
+			target += "?" + c.Request.URL.RawQuery
+		}
+		http.Redirect(c.Writer, c.Request, target, http.StatusMovedPermanently)
+		return Response{Handled: true}
+	}
+
+	fs := FSAdapter.RootPath(froot)
+	dir = path.Clean("/" + dir)
+	if config.Index != "" {
+		index := path.Join(dir, config.Index)
+		if stat, err := fs.Stat(index); err == nil && !stat.IsDir() {
+			serveFile(c, froot, index, config)
+			return Response{Handled: true}
+		}
+	}
+
+	infos, err := afero.ReadDir(fs, dir)
+	if err != nil {
+		return c.Fail(err)
+	}
+	entries := []DirectoryEntry{}
+	for _, info := range infos {
+		if strings.HasPrefix(info.Name(), ".") {
+			continue
+		}
+		entries = append(entries, DirectoryEntry{
+			Name:     info.Name(),
+			Size:     info.Size(),
+			Dir:      info.IsDir(),
+			Modified: info.ModTime(),
+		})
+	}
+
+	if config.Listing == JSONListing {
+		return c.R(entries)
+	}
+	if !strings.HasSuffix(urlPath, "/") {
+		urlPath += "/"
+	}
+	type listingEntry struct {
+		DirectoryEntry
+		Href string
+	}
+	links := make([]listingEntry, len(entries))
+	for i, entry := range entries {
+		target := urlPath + entry.Name
+		if entry.Dir {
+			target += "/"
+		}
+		links[i] = listingEntry{entry, (&url.URL{Path: target}).String()}
+	}
+	var buf bytes.Buffer
+	err = listingTemplate.Execute(&buf, map[string]interface{}{
+		"Path":    urlPath,
+		"Entries": links,
+	})
+	if err != nil {
+		return c.Fail(err)
+	}
+	c.Response.Header.Set("Content-Type", "text/html; charset=utf-8")
+	return c.Raw(buf.Bytes())
+}
@@ -0,0 +1,376 @@
+package celerity
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+func TestStaticFileHeaders(t *testing.T) {
+	server := New()
+	adapter := NewMEMAdapter()
+	FSAdapter = adapter
+	afero.WriteFile(adapter.MEMFS, "/public/files/test.txt", []byte("0123456789"), 0755)
+	server.ServePathWithConfig("/files", "/public/files", StaticConfig{MaxAge: time.Hour})
+	server.ServeFileWithConfig("/file", "/public/files/test.txt", StaticConfig{
+		CacheControl: "no-cache",
+	})
+
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	request := func(method, path string, header http.Header) (*http.Response, string) {
+		req, _ := http.NewRequest(method, ts.URL+path, nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Error requesting url: %s", err.Error())
+		}
+		defer res.Body.Close()
+		bbody, _ := ioutil.ReadAll(res.Body)
+		return res, string(bbody)
+	}
+
+	t.Run("cache headers", func(t *testing.T) {
+		res, body := request(GET, "/files/test.txt", nil)
+		if body != "0123456789" {
+			t.Errorf("body was: %s", body)
+		}
+		if v := res.Header.Get("Cache-Control"); v != "public, max-age=3600" {
+			t.Errorf("cache control was %s", v)
+		}
+		if res.Header.Get("Last-Modified") == "" {
+			t.Error("last modified not set")
+		}
+		if res.Header.Get("ETag") == "" {
+			t.Error("etag not set")
+		}
+		res, _ = request(GET, "/file", nil)
+		if v := res.Header.Get("Cache-Control"); v != "no-cache" {
+			t.Errorf("cache control was %s", v)
+		}
+	})
+
+	t.Run("range", func(t *testing.T) {
+		res, body := request(GET, "/files/test.txt", http.Header{"Range": {"bytes=2-4"}})
+		if res.StatusCode != http.StatusPartialContent {
+			t.Errorf("status code was %d", res.StatusCode)
+		}
+		if body != "234" {
+			t.Errorf("body was: %s", body)
+		}
+		if v := res.Header.Get("Content-Range"); v != "bytes 2-4/10" {
+			t.Errorf("content range was %s", v)
+		}
+	})
+
+	t.Run("multipart range", func(t *testing.T) {
+		res, body := request(GET, "/file", http.Header{"Range": {"bytes=0-1,8-9"}})
+		if res.StatusCode != http.StatusPartialContent {
+			t.Errorf("status code was %d", res.StatusCode)
+		}
+		if v := res.Header.Get("Content-Type"); !strings.HasPrefix(v, "multipart/byteranges") {
+			t.Errorf("content type was %s", v)
+		}
+		if !strings.Contains(body, "01") || !strings.Contains(body, "89") {
+			t.Errorf("body was: %s", body)
+		}
+	})
+
+	t.Run("if range", func(t *testing.T) {
+		res, _ := request(GET, "/files/test.txt", nil)
+		etag := res.Header.Get("ETag")
+		if strings.HasPrefix(etag, "W/") {
+			t.Errorf("etag was weak: %s", etag)
+		}
+		res, body := request(GET, "/files/test.txt", http.Header{
+			"Range":    {"bytes=2-4"},
+			"If-Range": {etag},
+		})
+		if res.StatusCode != http.StatusPartialContent {
+			t.Errorf("status code was %d", res.StatusCode)
+		}
+		if body != "234" {
+			t.Errorf("body was: %s", body)
+		}
+		res, body = request(GET, "/files/test.txt", http.Header{
+			"Range":    {"bytes=2-4"},
+			"If-Range": {`"stale"`},
+		})
+		if res.StatusCode != 200 || body != "0123456789" {
+			t.Errorf("stale if-range returned %d: %s", res.StatusCode, body)
+		}
+	})
+
+	t.Run("if none match", func(t *testing.T) {
+		res, _ := request(GET, "/files/test.txt", nil)
+		etag := res.Header.Get("ETag")
+		res, body := request(GET, "/files/test.txt", http.Header{"If-None-Match": {etag}})
+		if res.StatusCode != http.StatusNotModified {
+			t.Errorf("status code was %d", res.StatusCode)
+		}
+		if body != "" {
+			t.Errorf("body was: %s", body)
+		}
+	})
+
+	t.Run("if modified since", func(t *testing.T) {
+		res, _ := request(GET, "/files/test.txt", nil)
+		lm := res.Header.Get("Last-Modified")
+		res, _ = request(GET, "/files/test.txt", http.Header{"If-Modified-Since": {lm}})
+		if res.StatusCode != http.StatusNotModified {
+			t.Errorf("status code was %d", res.StatusCode)
+		}
+	})
+
+	t.Run("head", func(t *testing.T) {
+		res, body := request(HEAD, "/files/test.txt", nil)
+		if res.StatusCode != 200 {
+			t.Errorf("status code was %d", res.StatusCode)
+		}
+		if v := res.Header.Get("Content-Length"); v != "10" {
+			t.Errorf("content length was %s", v)
+		}
+		if body != "" {
+			t.Errorf("body was: %s", body)
+		}
+	})
+}
+
+func TestStaticDirectories(t *testing.T) {
+	server := New()
+	adapter := NewMEMAdapter()
+	FSAdapter = adapter
+	afero.WriteFile(adapter.MEMFS, "/site/docs/index.html", []byte("<p>docs</p>"), 0755)
+	afero.WriteFile(adapter.MEMFS, "/site/files/a.txt", []byte("a"), 0755)
+	afero.WriteFile(adapter.MEMFS, "/site/files/.hidden", []byte("hidden"), 0755)
+	afero.WriteFile(adapter.MEMFS, "/site/files/what?#is 100%.txt", []byte("odd"), 0755)
+	adapter.MEMFS.MkdirAll("/site/files/sub", 0755)
+	afero.WriteFile(adapter.MEMFS, "/secret.txt", []byte("secret"), 0755)
+	adapter.MEMFS.MkdirAll("/site/empty", 0755)
+
+	server.ServePathWithConfig("/site", "/site", StaticConfig{
+		Index:                 "index.html",
+		RedirectTrailingSlash: true,
+	})
+	server.ServePathWithConfig("/listing", "/site", StaticConfig{Listing: HTMLListing})
+	server.ServePathWithConfig("/json", "/site", StaticConfig{Listing: JSONListing})
+
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	get := func(path string) (*http.Response, string) {
+		res, err := client.Get(ts.URL + path)
+		if err != nil {
+			t.Fatalf("Error requesting url: %s", err.Error())
+		}
+		defer res.Body.Close()
+		bbody, _ := ioutil.ReadAll(res.Body)
+		return res, string(bbody)
+	}
+
+	t.Run("index", func(t *testing.T) {
+		res, body := get("/site/docs/")
+		if res.StatusCode != 200 || body != "<p>docs</p>" {
+			t.Errorf("status %d body was: %s", res.StatusCode, body)
+		}
+	})
+
+	t.Run("redirect", func(t *testing.T) {
+		res, _ := get("/site/docs?page=1")
+		if res.StatusCode != http.StatusMovedPermanently {
+			t.Errorf("status code was %d", res.StatusCode)
+		}
+		if v := res.Header.Get("Location"); v != "/site/docs/?page=1" {
+			t.Errorf("location was %s", v)
+		}
+	})
+
+	t.Run("no index", func(t *testing.T) {
+		res, _ := get("/site/empty/")
+		if res.StatusCode != 404 {
+			t.Errorf("status code was %d", res.StatusCode)
+		}
+	})
+
+	t.Run("html listing", func(t *testing.T) {
+		res, body := get("/listing/files/")
+		if res.StatusCode != 200 {
+			t.Errorf("status code was %d", res.StatusCode)
+		}
+		if !strings.Contains(body, `<a href="/listing/files/a.txt">a.txt</a>`) ||
+			!strings.Contains(body, `<a href="/listing/files/sub/">sub/</a>`) {
+			t.Errorf("body was: %s", body)
+		}
+		if !strings.Contains(body, `<a href="/listing/files/what%3F%23is%20100%25.txt">what?#is 100%.txt</a>`) {
+			t.Errorf("href was not escaped: %s", body)
+		}
+		if strings.Contains(body, ".hidden") {
+			t.Errorf("hidden file listed: %s", body)
+		}
+	})
+
+	t.Run("json listing", func(t *testing.T) {
+		res, body := get("/json/files")
+		if res.StatusCode != 200 {
+			t.Errorf("status code was %d", res.StatusCode)
+		}
+		if !strings.Contains(body, `"name":"a.txt"`) {
+			t.Errorf("body was: %s", body)
+		}
+	})
+
+	t.Run("traversal", func(t *testing.T) {
+		res, body := get("/listing/../secret.txt")
+		if strings.Contains(body, "secret") && res.StatusCode == 200 {
+			t.Errorf("file outside root served: %s", body)
+		}
+		res, body = get("/listing/%2e%2e/")
+		if strings.Contains(body, "secret.txt") {
+			t.Errorf("directory outside root listed: %s", body)
+		}
+	})
+}
+
+func TestServeSPA(t *testing.T) {
+	server := New()
+	adapter := NewMEMAdapter()
+	FSAdapter = adapter
+	afero.WriteFile(adapter.MEMFS, "/app/index.html", []byte("<div id=app></div>"), 0755)
+	afero.WriteFile(adapter.MEMFS, "/app/assets/app.js", []byte("render()"), 0755)
+
+	server.ServeSPA("/", "/app", "index.html")
+	server.GET("/health", func(c Context) Response {
+		return c.R("ok")
+	})
+	api := server.Scope("/api")
+	api.GET("/users", func(c Context) Response {
+		return c.R("users")
+	})
+
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	request := func(method, path, accept string) (*http.Response, string) {
+		req, _ := http.NewRequest(method, ts.URL+path, nil)
+		req.Header.Set("Accept", accept)
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Error requesting url: %s", err.Error())
+		}
+		defer res.Body.Close()
+		bbody, _ := ioutil.ReadAll(res.Body)
+		return res, string(bbody)
+	}
+
+	t.Run("asset", func(t *testing.T) {
+		res, body := request(GET, "/assets/app.js", "*/*")
+		if res.StatusCode != 200 || body != "render()" {
+			t.Errorf("status %d body was: %s", res.StatusCode, body)
+		}
+	})
+
+	t.Run("deep link", func(t *testing.T) {
+		res, body := request(GET, "/dashboard/settings", "text/html,application/xhtml+xml;q=0.9")
+		if res.StatusCode != 200 || body != "<div id=app></div>" {
+			t.Errorf("status %d body was: %s", res.StatusCode, body)
+		}
+		if v := res.Header.Get("Cache-Control"); v != "no-cache" {
+			t.Errorf("cache control was %s", v)
+		}
+	})
+
+	t.Run("not html", func(t *testing.T) {
+		res, _ := request(GET, "/dashboard", "application/json")
+		if res.StatusCode != 404 {
+			t.Errorf("status code was %d", res.StatusCode)
+		}
+		res, _ = request(GET, "/assets/missing.js", "*/*")
+		if res.StatusCode != 404 {
+			t.Errorf("status code was %d", res.StatusCode)
+		}
+	})
+
+	t.Run("not get", func(t *testing.T) {
+		res, _ := request(POST, "/dashboard", "text/html")
+		if res.StatusCode != 404 {
+			t.Errorf("status code was %d", res.StatusCode)
+		}
+	})
+
+	t.Run("routes take precedence", func(t *testing.T) {
+		_, body := request(GET, "/health", "text/html")
+		if !strings.Contains(body, `"ok"`) {
+			t.Errorf("body was: %s", body)
+		}
+		_, body = request(GET, "/api/users", "text/html")
+		if !strings.Contains(body, `"users"`) {
+			t.Errorf("body was: %s", body)
+		}
+		res, body := request(GET, "/api/missing", "text/html")
+		if res.StatusCode != 404 || strings.Contains(body, "id=app") {
+			t.Errorf("status %d body was: %s", res.StatusCode, body)
+		}
+	})
+}
+
+func TestStaticPrecompressed(t *testing.T) {
+	server := New()
+	adapter := NewMEMAdapter()
+	FSAdapter = adapter
+	afero.WriteFile(adapter.MEMFS, "/public/app.css", []byte("body {}"), 0755)
+	afero.WriteFile(adapter.MEMFS, "/public/app.css.gz", gzipped(t, "body {}"), 0755)
+	afero.WriteFile(adapter.MEMFS, "/public/plain.css", []byte("p {}"), 0755)
+	server.ServePathWithConfig("/public", "/public", StaticConfig{Precompressed: true})
+
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	get := func(path, encoding string) (*http.Response, string) {
+		req, _ := http.NewRequest(GET, ts.URL+path, nil)
+		req.Header.Set("Accept-Encoding", encoding)
+		res, err := http.DefaultTransport.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("Error requesting url: %s", err.Error())
+		}
+		defer res.Body.Close()
+		bbody, _ := ioutil.ReadAll(res.Body)
+		return res, string(bbody)
+	}
+
+	res, body := get("/public/app.css", "gzip, deflate")
+	if v := res.Header.Get("Content-Encoding"); v != "gzip" {
+		t.Errorf("content encoding was %s", v)
+	}
+	if v := res.Header.Get("Content-Type"); v != "text/css; charset=utf-8" {
+		t.Errorf("content type was %s", v)
+	}
+	if v := res.Header.Get("Vary"); v != "Accept-Encoding" {
+		t.Errorf("vary was %s", v)
+	}
+	if body == "body {}" {
+		t.Error("body was not compressed")
+	}
+
+	res, body = get("/public/app.css", "gzip;q=0")
+	if v := res.Header.Get("Content-Encoding"); v != "" || body != "body {}" {
+		t.Errorf("encoding %s body was: %s", v, body)
+	}
+
+	res, body = get("/public/plain.css", "gzip")
+	if v := res.Header.Get("Content-Encoding"); v != "" || body != "p {}" {
+		t.Errorf("encoding %s body was: %s", v, body)
+	}
+}
@@ -0,0 +1,229 @@
+package celerity
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// certCheckInterval is the minimum time between checks of the certificate
+// files for changes.
+var certCheckInterval = time.Second
+
+// TLSConfig configures TLS for StartTLS.
+type TLSConfig struct {
+	// CertFile and KeyFile are the paths to the PEM encoded certificate and
+	// private key. The files are reloaded when they change on disk.
+	CertFile string
+	KeyFile  string
+	// MinVersion is the minimum TLS version accepted, such as "1.2" or "1.3".
+	// It defaults to "1.2".
+	MinVersion string
+	// CipherSuites limits the cipher suites used for TLS 1.2 connections by
+	// name, such as "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256". The Go defaults
+	// are used when empty.
+	CipherSuites []string
+	// ClientCAFile is the path to a PEM encoded bundle of certificate
+	// authorities used to verify client certificates.
+	ClientCAFile string
+	// ClientAuth sets the policy for client certificates. It defaults to
+	// tls.RequireAndVerifyClientCert when ClientCAFile is set.
+	ClientAuth tls.ClientAuthType
+}
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+var tlsCipherSuites = map[string]uint16{
+	"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256":       tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384":       tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	"TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256": tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+	"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256":         tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384":         tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	"TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256":   tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+	"TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA":          tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA,
+	"TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA":          tls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA,
+	"TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA":            tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA,
+	"TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA":            tls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA,
+}
+
+// tlsConfigFromViper reads the TLS configuration set by the run command flags
+// or the config file.
+func tlsConfigFromViper() TLSConfig {
+	return TLSConfig{
+		CertFile:     viper.GetString("tls-cert"),
+		KeyFile:      viper.GetString("tls-key"),
+		MinVersion:   viper.GetString("tls-min-version"),
+		CipherSuites: viper.GetStringSlice("tls-ciphers"),
+		ClientCAFile: viper.GetString("tls-client-ca"),
+	}
+}
+
+// build creates a tls.Config from the configuration. The certificate is served
+// through GetCertificate so it can be reloaded.
+func (tc TLSConfig) build() (*tls.Config, error) {
+	if tc.CertFile == "" || tc.KeyFile == "" {
+		return nil, errors.New("a certificate and key file are required for TLS")
+	}
+	reloader, err := newCertReloader(tc.CertFile, tc.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	config := &tls.Config{
+		GetCertificate: reloader.GetCertificate,
+		MinVersion:     tls.VersionTLS12,
+	}
+	if tc.MinVersion != "" {
+		v, ok := tlsVersions[tc.MinVersion]
+		if !ok {
+			return nil, fmt.Errorf("unknown TLS version: %s", tc.MinVersion)
+		}
+		config.MinVersion = v
+	}
+	for _, name := range tc.CipherSuites {
+		id, ok := tlsCipherSuites[strings.ToUpper(strings.TrimSpace(name))]
+		if !ok {
+			return nil, fmt.Errorf("unknown cipher suite: %s", name)
+		}
+		config.CipherSuites = append(config.CipherSuites, id)
+	}
+	if tc.ClientCAFile != "" {
+		buf, err := ioutil.ReadFile(tc.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(buf) {
+			return nil, fmt.Errorf("no certificates found in %s", tc.ClientCAFile)
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	if tc.ClientAuth != tls.NoClientCert {
+		config.ClientAuth = tc.ClientAuth
+	}
+	return config, nil
+}
+
+// StartTLS starts the server using TLS. Like Start it runs the start hooks
+// before binding and blocks until the server fails or is stopped with
+// Shutdown.
+func (s *Server) StartTLS(host string, config TLSConfig) error {
+	if err := s.runStartHooks(); err != nil {
+		return err
+	}
+	listeners, err := Listen(host)
+	if err != nil {
+		return err
+	}
+	return s.ServeTLS(config, listeners...)
+}
+
+// ServeTLS serves TLS connections on the given listeners until the server
+// fails or is stopped with Shutdown.
+func (s *Server) ServeTLS(config TLSConfig, listeners ...net.Listener) error {
+	tlsConfig, err := config.build()
+	if err != nil {
+		closeListeners(listeners)
+		return err
+	}
+	if err := s.runStartHooks(); err != nil {
+		closeListeners(listeners)
+		return err
+	}
+	hs, err := s.newHTTPServer(s, tlsConfig)
+	if err != nil {
+		closeListeners(listeners)
+		return err
+	}
+	return s.serveListeners(hs, listeners, true, true)
+}
+
+// PeerCertificate returns the verified certificate presented by the client. It
+// returns nil if the request was not made over TLS or the client did not send
+// a certificate.
+func (c *Context) PeerCertificate() *x509.Certificate {
+	if c.Request == nil || c.Request.TLS == nil || len(c.Request.TLS.PeerCertificates) == 0 {
+		return nil
+	}
+	return c.Request.TLS.PeerCertificates[0]
+}
+
+// certReloader serves a certificate and reloads it when the certificate or key
+// file changes.
+type certReloader struct {
+	certFile string
+	keyFile  string
+	mx       sync.Mutex
+	cert     *tls.Certificate
+	modTime  time.Time
+	checked  time.Time
+}
+
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	r := &certReloader{certFile: certFile, keyFile: keyFile}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// load reads the certificate and key from disk.
+func (r *certReloader) load() error {
+	modTime, err := r.lastModified()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+	r.cert = &cert
+	r.modTime = modTime
+	return nil
+}
+
+// lastModified returns the latest modification time of the certificate and
+// key files.
+func (r *certReloader) lastModified() (time.Time, error) {
+	var latest time.Time
+	for _, f := range []string{r.certFile, r.keyFile} {
+		stat, err := os.Stat(f)
+		if err != nil {
+			return latest, err
+		}
+		if stat.ModTime().After(latest) {
+			latest = stat.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// GetCertificate returns the current certificate, reloading it first if the
+// files have changed. If the new files cannot be loaded the previous
+// certificate continues to be served.
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	if time.Since(r.checked) < certCheckInterval {
+		return r.cert, nil
+	}
+	r.checked = time.Now()
+	if modTime, err := r.lastModified(); err == nil && !modTime.Equal(r.modTime) {
+		r.load()
+	}
+	return r.cert, nil
+}
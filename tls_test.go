@@ -0,0 +1,183 @@
+package celerity
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testCert creates a certificate signed by parent, or a self signed CA when
+// parent is nil.
+func testCert(t *testing.T, cn string, serial int64, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey, []byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	cert, _ := x509.ParseCertificate(der)
+	keyDer, _ := x509.MarshalECPrivateKey(key)
+	return cert, key,
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+}
+
+func TestStartTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "celerity-tls")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer os.RemoveAll(dir)
+	file := func(name string, data []byte) string {
+		p := filepath.Join(dir, name)
+		ioutil.WriteFile(p, data, 0600)
+		return p
+	}
+
+	ca, caKey, caPEM, _ := testCert(t, "ca", 1, nil, nil)
+	_, _, certPEM, keyPEM := testCert(t, "server", 2, ca, caKey)
+	_, _, clientPEM, clientKeyPEM := testCert(t, "client", 3, ca, caKey)
+	caFile := file("ca.pem", caPEM)
+	certFile := file("cert.pem", certPEM)
+	keyFile := file("key.pem", keyPEM)
+	clientCert, _ := tls.X509KeyPair(clientPEM, clientKeyPEM)
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(caPEM)
+
+	server := New()
+	server.GET("/cn", func(c Context) Response {
+		if cert := c.PeerCertificate(); cert != nil {
+			return c.Raw([]byte(cert.Subject.CommonName))
+		}
+		return c.Raw([]byte("none"))
+	})
+
+	servers := []*Server{}
+	defer func() {
+		for _, svr := range servers {
+			svr.Shutdown(context.Background())
+		}
+	}()
+	start := func(config TLSConfig) string {
+		addr := freeAddr(t)
+		svr := New()
+		svr.Router = server.Router
+		servers = append(servers, svr)
+		go svr.StartTLS(addr, config)
+		waitForServer(t, addr)
+		return addr
+	}
+	get := func(addr string, clientConfig *tls.Config) (*http.Response, string, error) {
+		client := &http.Client{Transport: &http.Transport{
+			TLSClientConfig:   clientConfig,
+			DisableKeepAlives: true,
+		}}
+		res, err := client.Get("https://" + addr + "/cn")
+		if err != nil {
+			return nil, "", err
+		}
+		defer res.Body.Close()
+		b, _ := ioutil.ReadAll(res.Body)
+		return res, string(b), nil
+	}
+
+	t.Run("serve", func(t *testing.T) {
+		addr := start(TLSConfig{CertFile: certFile, KeyFile: keyFile})
+		res, body, err := get(addr, &tls.Config{RootCAs: roots})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if body != "none" || res.TLS.PeerCertificates[0].Subject.CommonName != "server" {
+			t.Errorf("body was: %s", body)
+		}
+	})
+
+	t.Run("min version", func(t *testing.T) {
+		addr := start(TLSConfig{CertFile: certFile, KeyFile: keyFile, MinVersion: "1.3"})
+		_, _, err := get(addr, &tls.Config{RootCAs: roots, MaxVersion: tls.VersionTLS12})
+		if err == nil {
+			t.Error("TLS 1.2 connection was accepted")
+		}
+	})
+
+	t.Run("client certificates", func(t *testing.T) {
+		addr := start(TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: caFile})
+		if _, _, err := get(addr, &tls.Config{RootCAs: roots}); err == nil {
+			t.Error("connection without a client certificate was accepted")
+		}
+		_, body, err := get(addr, &tls.Config{RootCAs: roots, Certificates: []tls.Certificate{clientCert}})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if body != "client" {
+			t.Errorf("body was: %s", body)
+		}
+	})
+
+	t.Run("reload", func(t *testing.T) {
+		defer func(d time.Duration) { certCheckInterval = d }(certCheckInterval)
+		certCheckInterval = 0
+		reloadCert := file("reload-cert.pem", certPEM)
+		reloadKey := file("reload-key.pem", keyPEM)
+		addr := start(TLSConfig{CertFile: reloadCert, KeyFile: reloadKey})
+
+		_, _, newCertPEM, newKeyPEM := testCert(t, "reloaded", 4, ca, caKey)
+		ioutil.WriteFile(reloadCert, newCertPEM, 0600)
+		ioutil.WriteFile(reloadKey, newKeyPEM, 0600)
+		later := time.Now().Add(time.Minute)
+		os.Chtimes(reloadCert, later, later)
+		os.Chtimes(reloadKey, later, later)
+
+		res, _, err := get(addr, &tls.Config{RootCAs: roots})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if cn := res.TLS.PeerCertificates[0].Subject.CommonName; cn != "reloaded" {
+			t.Errorf("certificate was %s", cn)
+		}
+	})
+
+	t.Run("invalid config", func(t *testing.T) {
+		configs := []TLSConfig{
+			{},
+			{CertFile: certFile, KeyFile: keyFile, MinVersion: "2.0"},
+			{CertFile: certFile, KeyFile: keyFile, CipherSuites: []string{"TLS_NOPE"}},
+			{CertFile: certFile, KeyFile: keyFile, ClientCAFile: keyFile},
+		}
+		for _, c := range configs {
+			if err := New().StartTLS("127.0.0.1:0", c); err == nil || strings.Contains(err.Error(), "closed") {
+				t.Errorf("config %+v was accepted: %v", c, err)
+			}
+		}
+	})
+}
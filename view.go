@@ -0,0 +1,241 @@
+package celerity
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/spf13/afero"
+)
+
+// ViewEngine renders HTML templates using html/template. Templates are loaded
+// from the Root path using the FSAdapter.
+//
+// A view is rendered inside a layout from the LayoutDir. The layout includes
+// the view using the "content" template. Every template in the PartialDir is
+// available to layouts and views by its path without the extension.
+//
+//	<!-- layouts/main.html -->
+//	<html>
+//		<body>
+//			{{ template "partials/nav" . }}
+//			{{ template "content" . }}
+//		</body>
+//	</html>
+//
+// Parsed templates are cached in environments with CacheTemplates set, such as
+// PROD. In other environments templates are parsed on every render so changes
+// are picked up without a restart.
+type ViewEngine struct {
+	Root          string
+	LayoutDir     string
+	PartialDir    string
+	Extension     string
+	DefaultLayout string
+	Funcs         template.FuncMap
+	server        *Server
+	cache         map[string]*template.Template
+	mx            sync.RWMutex
+}
+
+// NewViewEngine creates a new view engine for templates under the root path.
+func NewViewEngine(root string) *ViewEngine {
+	return &ViewEngine{
+		Root:          root,
+		LayoutDir:     "layouts",
+		PartialDir:    "partials",
+		Extension:     ".html",
+		DefaultLayout: "main",
+		Funcs:         template.FuncMap{},
+		cache:         map[string]*template.Template{},
+	}
+}
+
+// SetViews sets the view engine used by Context.Render.
+//
+//	svr := celerity.New()
+//	svr.SetViews(celerity.NewViewEngine("./views"))
+func (s *Server) SetViews(v *ViewEngine) {
+	v.server = s
+	s.Views = v
+}
+
+// Render renders a view inside the default layout and returns it as an HTML
+// response.
+//
+//	func showUser(c celerity.Context) celerity.Response {
+//		return c.Render("users/show", user)
+//	}
+func (c *Context) Render(name string, data interface{}) Response {
+	if c.Server == nil || c.Server.Views == nil {
+		return c.Fail(errors.New("no view engine configured"))
+	}
+	return c.RenderLayout(c.Server.Views.DefaultLayout, name, data)
+}
+
+// RenderLayout renders a view inside a specific layout. An empty layout
+// renders the view on its own.
+func (c *Context) RenderLayout(layout, name string, data interface{}) Response {
+	if c.Server == nil || c.Server.Views == nil {
+		return c.Fail(errors.New("no view engine configured"))
+	}
+	var buf bytes.Buffer
+	if err := c.Server.Views.Render(&buf, layout, name, data); err != nil {
+		return c.Fail(err)
+	}
+	c.Response.Header.Set("Content-Type", "text/html; charset=utf-8")
+	return c.Raw(buf.Bytes())
+}
+
+// Render executes a view inside a layout and writes the output to w.
+func (v *ViewEngine) Render(w io.Writer, layout, name string, data interface{}) error {
+	t, err := v.template(layout, name)
+	if err != nil {
+		return err
+	}
+	if layout == "" {
+		return t.ExecuteTemplate(w, "content", data)
+	}
+	return t.ExecuteTemplate(w, "layout", data)
+}
+
+func (v *ViewEngine) template(layout, name string) (*template.Template, error) {
+	key := layout + ":" + name
+	caching := CurrentEnvironment().CacheTemplates
+	if caching {
+		v.mx.RLock()
+		t, ok := v.cache[key]
+		v.mx.RUnlock()
+		if ok {
+			return t, nil
+		}
+	}
+
+	t, err := v.parse(layout, name)
+	if err != nil {
+		return nil, err
+	}
+	if caching {
+		v.mx.Lock()
+		v.cache[key] = t
+		v.mx.Unlock()
+	}
+	return t, nil
+}
+
+// parse builds the template set for a view, its layout and all partials.
+func (v *ViewEngine) parse(layout, name string) (*template.Template, error) {
+	fs := FSAdapter.RootPath(v.Root)
+	t := template.New("layout").Funcs(v.funcs())
+
+	if layout != "" {
+		src, err := afero.ReadFile(fs, v.file(path.Join(v.LayoutDir, layout)))
+		if err != nil {
+			return nil, fmt.Errorf("could not read layout %s: %s", layout, err.Error())
+		}
+		if _, err := t.Parse(string(src)); err != nil {
+			return nil, err
+		}
+	}
+
+	partials := "/" + v.PartialDir
+	if _, err := fs.Stat(partials); err == nil {
+		err := afero.Walk(fs, partials, func(p string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() || filepath.Ext(p) != v.Extension {
+				return err
+			}
+			src, err := afero.ReadFile(fs, p)
+			if err != nil {
+				return err
+			}
+			pname := strings.TrimSuffix(strings.TrimPrefix(filepath.ToSlash(p), "/"), v.Extension)
+			_, err = t.New(pname).Parse(string(src))
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	src, err := afero.ReadFile(fs, v.file(name))
+	if err != nil {
+		return nil, fmt.Errorf("could not read view %s: %s", name, err.Error())
+	}
+	if _, err := t.New("content").Parse(string(src)); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (v *ViewEngine) file(name string) string {
+	return "/" + strings.TrimPrefix(name, "/") + v.Extension
+}
+
+// funcs returns the helper functions available to templates.
+//
+// url builds a path from a route path by replacing its parameters in order:
+//
+//	<a href="{{ url "/users/:id" .ID }}">profile</a>
+//
+// asset returns the fingerprinted URL of a static asset:
+//
+//	<script src="{{ asset "app.js" }}"></script>
+func (v *ViewEngine) funcs() template.FuncMap {
+	fm := template.FuncMap{
+		"url": URL,
+		"asset": func(name string) string {
+			if v.server == nil {
+				return name
+			}
+			return v.server.AssetURL(name)
+		},
+	}
+	for k, f := range v.Funcs {
+		fm[k] = f
+	}
+	return fm
+}
+
+// SafeHTML marks a string as trusted HTML so it is not escaped. It is not
+// available to templates by default and can be added as a helper when needed.
+// It must never be used with user input.
+//
+//	views.Funcs["safe"] = celerity.SafeHTML
+func SafeHTML(s string) template.HTML {
+	return template.HTML(s)
+}
+
+// URL builds a path from a route path by replacing its URL parameters and
+// wildcard with the given values in order. Values are path escaped. Slashes in
+// a wildcard value are kept as path separators.
+//
+//	celerity.URL("/users/:id/posts/:post", 1, 20) // "/users/1/posts/20"
+func URL(routePath string, params ...interface{}) string {
+	tokens := strings.Split(routePath, "/")
+	for i, t := range tokens {
+		if len(params) == 0 {
+			break
+		}
+		switch {
+		case len(t) > 0 && t[0] == ':':
+			tokens[i] = url.PathEscape(fmt.Sprint(params[0]))
+			params = params[1:]
+		case t == "*":
+			segments := strings.Split(fmt.Sprint(params[0]), "/")
+			for j, s := range segments {
+				segments[j] = url.PathEscape(s)
+			}
+			tokens[i] = strings.Join(segments, "/")
+			params = params[1:]
+		}
+	}
+	return strings.Join(tokens, "/")
+}
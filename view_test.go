@@ -0,0 +1,133 @@
+package celerity
+
+import (
+	"html/template"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/viper"
+)
+
+func TestURL(t *testing.T) {
+	if v := URL("/users/:id/posts/:post", 1, "abc"); v != "/users/1/posts/abc" {
+		t.Errorf("url was %s", v)
+	}
+	if v := URL("/files/*", "a.txt"); v != "/files/a.txt" {
+		t.Errorf("url was %s", v)
+	}
+	if v := URL("/users/:id"); v != "/users/:id" {
+		t.Errorf("url was %s", v)
+	}
+	if v := URL("/users/:id/posts", "../admin?x=1#y"); v != "/users/..%2Fadmin%3Fx=1%23y/posts" {
+		t.Errorf("param was not escaped: %s", v)
+	}
+	if v := URL("/files/*", "docs/my file.txt"); v != "/files/docs/my%20file.txt" {
+		t.Errorf("wildcard was not escaped: %s", v)
+	}
+}
+
+func TestRender(t *testing.T) {
+	adapter := NewMEMAdapter()
+	FSAdapter = adapter
+	afero.WriteFile(adapter.MEMFS, "/views/layouts/main.html",
+		[]byte(`<html>{{ template "partials/nav" . }}{{ template "content" . }}</html>`), 0755)
+	afero.WriteFile(adapter.MEMFS, "/views/partials/nav.html",
+		[]byte(`<nav>{{ upper "menu" }}</nav>`), 0755)
+	afero.WriteFile(adapter.MEMFS, "/views/users/show.html",
+		[]byte(`<a href="{{ url "/users/:id" .ID }}">{{ .Name }}</a>`), 0755)
+
+	server := New()
+	views := NewViewEngine("/views")
+	views.Funcs = template.FuncMap{"upper": strings.ToUpper}
+	server.SetViews(views)
+	server.GET("/users/:id", func(c Context) Response {
+		return c.Render("users/show", map[string]interface{}{
+			"ID":   c.URLParams.Int("id"),
+			"Name": "<alice>",
+		})
+	})
+	server.GET("/bare", func(c Context) Response {
+		return c.RenderLayout("", "users/show", map[string]interface{}{"ID": 1})
+	})
+
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	get := func(path string) (*http.Response, string) {
+		res, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatalf("Error requesting url: %s", err.Error())
+		}
+		defer res.Body.Close()
+		bbody, _ := ioutil.ReadAll(res.Body)
+		return res, string(bbody)
+	}
+
+	t.Run("layout", func(t *testing.T) {
+		res, body := get("/users/3")
+		if v := res.Header.Get("Content-Type"); v != "text/html; charset=utf-8" {
+			t.Errorf("content type was %s", v)
+		}
+		expected := `<html><nav>MENU</nav><a href="/users/3">&lt;alice&gt;</a></html>`
+		if body != expected {
+			t.Errorf("body was: %s", body)
+		}
+	})
+
+	t.Run("no layout", func(t *testing.T) {
+		_, body := get("/bare")
+		if body != `<a href="/users/1"></a>` {
+			t.Errorf("body was: %s", body)
+		}
+	})
+
+	t.Run("reload in dev", func(t *testing.T) {
+		viper.Set("env", DEV)
+		afero.WriteFile(adapter.MEMFS, "/views/users/show.html", []byte(`changed`), 0755)
+		_, body := get("/bare")
+		if body != "changed" {
+			t.Errorf("body was: %s", body)
+		}
+	})
+
+	t.Run("cache in prod", func(t *testing.T) {
+		viper.Set("env", PROD)
+		defer viper.Set("env", DEV)
+		get("/bare")
+		afero.WriteFile(adapter.MEMFS, "/views/users/show.html", []byte(`changed again`), 0755)
+		_, body := get("/bare")
+		if body != "changed" {
+			t.Errorf("body was: %s", body)
+		}
+	})
+
+	t.Run("safe helper", func(t *testing.T) {
+		afero.WriteFile(adapter.MEMFS, "/views/raw.html", []byte(`{{ safe . }}`), 0755)
+		c := NewContext()
+		c.Server = server
+		if r := c.RenderLayout("", "raw", "<b>hi</b>"); r.StatusCode != 500 {
+			t.Errorf("safe should not be available by default: %d", r.StatusCode)
+		}
+		views.Funcs["safe"] = SafeHTML
+		defer delete(views.Funcs, "safe")
+		c = NewContext()
+		c.Server = server
+		r := c.RenderLayout("", "raw", "<b>hi</b>")
+		if body := string(r.Raw()); body != "<b>hi</b>" {
+			t.Errorf("body was: %s", body)
+		}
+	})
+
+	t.Run("missing view", func(t *testing.T) {
+		c := NewContext()
+		c.Server = server
+		r := c.Render("missing", nil)
+		if r.StatusCode != 500 {
+			t.Errorf("status code was %d", r.StatusCode)
+		}
+	})
+}